@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Bulk Selection ---
+
+// visiblePaths returns the paths of all list items: the filter results while filtering,
+// otherwise the files shown with the current hidden-files setting (on all pages).
+func (m *model) visiblePaths() []string {
+	paths := make([]string, 0, len(m.list.Items()))
+	for _, listItem := range m.list.Items() {
+		if li, ok := listItem.(item); ok {
+			paths = append(paths, li.name)
+		}
+	}
+	return paths
+}
+
+// isEffectivelySelected reports whether a copy would include relativePath.
+func (m *model) isEffectivelySelected(relativePath string) bool {
+	return m.selected[relativePath] || m.patternMatched[relativePath]
+}
+
+// deselectPaths unchecks paths. Files selected only by a pattern get an exclusion, just like
+// unchecking them one by one. It returns the number of files that were deselected.
+func (m *model) deselectPaths(paths []string) int {
+	changed := 0
+	for _, relativePath := range paths {
+		if !m.isEffectivelySelected(relativePath) {
+			continue
+		}
+		if m.patternMatched[relativePath] {
+			m.patterns = append(m.patterns, patternExcludePrefix+escapeGlob(relativePath))
+		}
+		delete(m.selected, relativePath)
+		changed++
+	}
+	return changed
+}
+
+// selectPaths checks paths and returns the number of files that were not selected before.
+func (m *model) selectPaths(paths []string) int {
+	changed := 0
+	for _, relativePath := range paths {
+		if !m.isEffectivelySelected(relativePath) {
+			changed++
+		}
+		m.selected[relativePath] = true
+	}
+	return changed
+}
+
+// applyBulkChange finishes a bulk operation: patterns are re-evaluated, the list is refreshed
+// (deselected hidden files may disappear), and the change is recorded for undo.
+func (m *model) applyBulkChange(description string, before selectionSnapshot, status string) tea.Cmd {
+	m.recomputePatternMatches()
+	m.recordUndo(description, before)
+	return m.setStatus(status)
+}
+
+// clearSelection unchecks every explicitly selected file. Patterns are kept; they are edited
+// in the pattern editor, so one key press never drops them unnoticed.
+func (m *model) clearSelection() tea.Cmd {
+	before := m.snapshotSelection()
+	cleared := len(m.selected)
+	clear(m.selected)
+	status := fmt.Sprintf("Cleared %d selected file(s)", cleared)
+	if len(m.patterns) > 0 {
+		status += fmt.Sprintf("; %d pattern(s) still select %d file(s)", len(m.patterns), len(m.patternMatched))
+	}
+	return m.applyBulkChange("clear selection", before, status)
+}
+
+// selectVisible checks every visible file.
+func (m *model) selectVisible() tea.Cmd {
+	before := m.snapshotSelection()
+	changed := m.selectPaths(m.visiblePaths())
+	return m.applyBulkChange("select visible", before, fmt.Sprintf("Selected %d visible file(s)", changed))
+}
+
+// deselectVisible unchecks every visible file.
+func (m *model) deselectVisible() tea.Cmd {
+	before := m.snapshotSelection()
+	changed := m.deselectPaths(m.visiblePaths())
+	return m.applyBulkChange("deselect visible", before, fmt.Sprintf("Deselected %d visible file(s)", changed))
+}
+
+// invertVisible flips the selection of every visible file.
+func (m *model) invertVisible() tea.Cmd {
+	before := m.snapshotSelection()
+	var toSelect, toDeselect []string
+	for _, relativePath := range m.visiblePaths() {
+		if m.isEffectivelySelected(relativePath) {
+			toDeselect = append(toDeselect, relativePath)
+		} else {
+			toSelect = append(toSelect, relativePath)
+		}
+	}
+	selected := m.selectPaths(toSelect)
+	deselected := m.deselectPaths(toDeselect)
+	return m.applyBulkChange("invert visible", before, fmt.Sprintf("Inverted the visible files (+%d, -%d)", selected, deselected))
+}
+
+// selectFilterMatches checks every file matching the filter query, including hidden files. In
+// normal mode the last filter query is used, so a query can be applied without retyping it.
+func (m *model) selectFilterMatches() tea.Cmd {
+	query := m.filterQuery
+	if !m.isFiltering {
+		query = m.lastFilterQuery
+	}
+	if query == "" {
+		return m.setStatus("No filter query to select matches of")
+	}
+	// While searching contents, the matches are the files found so far.
+	if m.isFiltering && m.search.active {
+		before := m.snapshotSelection()
+		changed := m.selectPaths(m.contentHitPaths())
+		return m.applyBulkChange(fmt.Sprintf("select content matches of '%s'", query), before, fmt.Sprintf("Selected %d file(s) containing '%s'", changed, query))
+	}
+	parsed, err := parseFilterQuery(query)
+	if err != nil {
+		return m.setStatus(fmt.Sprintf("Filter '%s': %v", query, err))
+	}
+	var paths []string
+	for _, match := range parsed.matchFiles(m.allAvailableFiles) {
+		paths = append(paths, match.path)
+	}
+	before := m.snapshotSelection()
+	changed := m.selectPaths(paths)
+	return m.applyBulkChange(fmt.Sprintf("select matches of '%s'", query), before, fmt.Sprintf("Selected %d file(s) matching '%s'", changed, query))
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Clipboard Access ---
+
+const (
+	// clipboardTimeout bounds how long a single clipboard command may run. Without it,
+	// a command talking to a dead X server can block forever.
+	clipboardTimeout = 5 * time.Second
+	// displayDialTimeout bounds the up-front reachability check of the display server.
+	displayDialTimeout = time.Second
+)
+
+// clipboardBackend describes an external command pair used to access the system clipboard.
+type clipboardBackend struct {
+	name     string       // Human-readable backend name (e.g., "xclip").
+	copyCmd  []string     // Command and arguments that read stdin into the clipboard.
+	pasteCmd []string     // Command and arguments that print the plain text of the clipboard to stdout.
+	requires func() error // Optional environment check (e.g., display reachable), nil if none.
+	// richCopy places the text/html rendering on the clipboard, together with text/plain where
+	// the backend can offer both in a single entry; nil if unsupported.
+	richCopy func(plain, html string) error
+	// richPasteCmd prints the text/html target. It is set for backends whose rich copy leaves
+	// no plain text on the clipboard, so verification has to read the HTML back instead.
+	richPasteCmd []string
+	// richNote explains where each format went for backends with richPasteCmd.
+	richNote string
+}
+
+// errRichUnsupported is returned by copyRichToClipboard when the active backend cannot
+// hold HTML. The plain text has still been copied in that case.
+var errRichUnsupported = errors.New("backend cannot hold HTML, copied plain text only")
+
+// clipboardBackends returns the candidate backends for the current OS in order of preference.
+func clipboardBackends() []clipboardBackend {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardBackend{
+			{name: "pbcopy", copyCmd: []string{"pbcopy"}, pasteCmd: []string{"pbpaste"}, richCopy: richCopyDarwin},
+		}
+	case "linux":
+		// wl-copy is only preferred when a Wayland compositor is reachable; otherwise
+		// the X11 tools are tried (also under XWayland).
+		return []clipboardBackend{
+			{
+				name:    "wl-copy",
+				copyCmd: []string{"wl-copy"},
+				// Ask for plain text explicitly: without --type, wl-paste picks any offered type.
+				pasteCmd:     []string{"wl-paste", "--no-newline", "--type", "text/plain"},
+				requires:     checkWaylandDisplay,
+				richCopy:     richCopyWayland,
+				richPasteCmd: []string{"wl-paste", "--no-newline", "--type", "text/html"},
+				richNote:     singleTargetRichNote,
+			},
+			{
+				name:         "xclip",
+				copyCmd:      []string{"xclip", "-selection", "clipboard"},
+				pasteCmd:     []string{"xclip", "-selection", "clipboard", "-t", "UTF8_STRING", "-o"},
+				requires:     checkX11Display,
+				richCopy:     richCopyX11,
+				richPasteCmd: []string{"xclip", "-selection", "clipboard", "-t", "text/html", "-o"},
+				richNote:     singleTargetRichNote,
+			},
+			{
+				name:     "xsel",
+				copyCmd:  []string{"xsel", "--clipboard", "--input"},
+				pasteCmd: []string{"xsel", "--clipboard", "--output"},
+				requires: checkX11Display,
+			},
+		}
+	case "windows":
+		// clip.exe is write-only, so reading goes through PowerShell.
+		return []clipboardBackend{
+			{
+				name:     "clip.exe",
+				copyCmd:  []string{"clip.exe"},
+				pasteCmd: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+				richCopy: richCopyWindows,
+			},
+		}
+	}
+	return nil
+}
+
+// checkClipboard returns the first installed backend whose environment requirements are
+// met, without touching the clipboard itself. If backends are installed but none is usable,
+// the requirement error of the most preferred one is returned.
+func checkClipboard() (clipboardBackend, error) {
+	backends := clipboardBackends()
+	if len(backends) == 0 {
+		return clipboardBackend{}, fmt.Errorf("clipboard OS unsupported: %s", runtime.GOOS)
+	}
+
+	var firstErr error
+	for _, b := range backends {
+		if _, err := exec.LookPath(b.copyCmd[0]); err != nil {
+			continue
+		}
+		if b.requires != nil {
+			if err := b.requires(); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s unusable: %w", b.name, err)
+				}
+				continue
+			}
+		}
+		return b, nil
+	}
+	if firstErr != nil {
+		return clipboardBackend{}, firstErr
+	}
+
+	if runtime.GOOS == "linux" {
+		return clipboardBackend{}, errors.New("clipboard dependency missing: requires 'wl-copy', 'xclip' or 'xsel' (e.g., 'sudo apt install xclip')")
+	}
+	return clipboardBackend{}, fmt.Errorf("clipboard dependency missing: '%s' not found in PATH", backends[0].copyCmd[0])
+}
+
+// copyToClipboard attempts to copy the given text to the system clipboard
+// using OS-specific commands. Currently supports macOS, Linux (wl-copy/xclip/xsel), Windows.
+func copyToClipboard(text string) error {
+	backend, err := checkClipboard()
+	if err != nil {
+		return err
+	}
+	_, err = runClipboardCommand(backend.copyCmd, text)
+	return err
+}
+
+// copyRichToClipboard places both the plain text and its HTML rendering on the clipboard.
+// Backends without HTML support fall back to plain text and return errRichUnsupported.
+func copyRichToClipboard(plain, html string) error {
+	backend, err := checkClipboard()
+	if err != nil {
+		return err
+	}
+	if backend.richCopy == nil {
+		if _, err := runClipboardCommand(backend.copyCmd, plain); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: %w", backend.name, errRichUnsupported)
+	}
+	return backend.richCopy(plain, html)
+}
+
+// singleTargetRichNote describes the rich copy of wl-copy and xclip, which serve exactly one
+// target per process: the last process to take a selection owns it.
+const singleTargetRichNote = "the clipboard holds text/html only, plain text goes to the primary selection (middle click)"
+
+// richCopyWayland places the plain text on the primary selection and then the HTML on the
+// clipboard as text/html. The HTML wins the clipboard, since that is what -html asks for; the
+// primary selection keeps plain text available to terminals.
+func richCopyWayland(plain, html string) error {
+	if _, err := runClipboardCommand([]string{"wl-copy", "--primary", "--type", "text/plain;charset=utf-8"}, plain); err != nil {
+		return err
+	}
+	_, err := runClipboardCommand([]string{"wl-copy", "--type", "text/html"}, html)
+	return err
+}
+
+// richCopyX11 is the xclip counterpart of richCopyWayland: plain text (UTF8_STRING) on the
+// PRIMARY selection, text/html on the CLIPBOARD selection.
+func richCopyX11(plain, html string) error {
+	if _, err := runClipboardCommand([]string{"xclip", "-selection", "primary", "-t", "UTF8_STRING"}, plain); err != nil {
+		return err
+	}
+	_, err := runClipboardCommand([]string{"xclip", "-selection", "clipboard", "-t", "text/html"}, html)
+	return err
+}
+
+// richCopyDarwin sets a single clipboard entry holding both flavours via AppleScript.
+// The data is hex-encoded so no AppleScript string escaping is needed.
+func richCopyDarwin(plain, html string) error {
+	script := fmt.Sprintf("set the clipboard to {«class HTML»:«data HTML%X», «class utf8»:«data utf8%X»}\n", html, plain)
+	_, err := runClipboardCommand([]string{"osascript", "-"}, script)
+	return err
+}
+
+// windowsRichCopyScript reads two base64 lines (plain text, CF_HTML) from stdin and sets
+// both formats on a single clipboard data object.
+const windowsRichCopyScript = `$ErrorActionPreference = 'Stop'
+Add-Type -AssemblyName System.Windows.Forms
+$parts = [Console]::In.ReadToEnd().Split([char]10)
+$utf8 = [System.Text.Encoding]::UTF8
+$data = New-Object System.Windows.Forms.DataObject
+$data.SetData([System.Windows.Forms.DataFormats]::UnicodeText, $utf8.GetString([Convert]::FromBase64String($parts[0])))
+$data.SetData([System.Windows.Forms.DataFormats]::Html, $utf8.GetString([Convert]::FromBase64String($parts[1])))
+[System.Windows.Forms.Clipboard]::SetDataObject($data, $true)`
+
+// richCopyWindows sets CF_UNICODETEXT and CF_HTML through PowerShell and .NET.
+func richCopyWindows(plain, html string) error {
+	input := base64.StdEncoding.EncodeToString([]byte(plain)) + "\n" +
+		base64.StdEncoding.EncodeToString([]byte(windowsCFHTML(html)))
+	_, err := runClipboardCommand([]string{"powershell.exe", "-NoProfile", "-STA", "-Command", windowsRichCopyScript}, input)
+	return err
+}
+
+// windowsCFHTML wraps an HTML document in the CF_HTML clipboard format, whose header
+// records byte offsets of the document and of the fragment (the <body> content).
+func windowsCFHTML(doc string) string {
+	const headerFormat = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+	const startMarker, endMarker = "<!--StartFragment-->", "<!--EndFragment-->"
+
+	// Place the fragment markers just inside <body> ... </body> when present.
+	if i := strings.Index(doc, "<body>"); i >= 0 {
+		doc = doc[:i+len("<body>")] + startMarker + doc[i+len("<body>"):]
+	} else {
+		doc = startMarker + doc
+	}
+	if i := strings.LastIndex(doc, "</body>"); i >= 0 {
+		doc = doc[:i] + endMarker + doc[i:]
+	} else {
+		doc += endMarker
+	}
+
+	headerLen := len(fmt.Sprintf(headerFormat, 0, 0, 0, 0))
+	startFragment := headerLen + strings.Index(doc, startMarker) + len(startMarker)
+	endFragment := headerLen + strings.Index(doc, endMarker)
+	return fmt.Sprintf(headerFormat, headerLen, headerLen+len(doc), startFragment, endFragment) + doc
+}
+
+// readFromClipboard returns the current text content of the system clipboard
+// using the paste counterpart of the backend used by copyToClipboard.
+func readFromClipboard() (string, error) {
+	backend, err := checkClipboard()
+	if err != nil {
+		return "", err
+	}
+	return runClipboardCommand(backend.pasteCmd, "")
+}
+
+// verifyClipboard reads the plain text of the clipboard back with the paste command matching
+// the backend that wrote it and compares a SHA-256 hash against the expected text. Some xclip
+// builds silently truncate large inputs, which this catches. A clipboard without a plain-text
+// target fails too, since the paste command reports an error then.
+func verifyClipboard(expected string) error {
+	backend, err := checkClipboard()
+	if err != nil {
+		return err
+	}
+	return verifyClipboardWith(backend.pasteCmd, expected)
+}
+
+// verifyRichClipboard verifies a copy made by copyRichToClipboard. Where the rich copy left
+// only text/html on the clipboard (see richPasteCmd), the HTML is read back and compared;
+// otherwise the plain text is, as in verifyClipboard.
+func verifyRichClipboard(plain, html string) error {
+	backend, err := checkClipboard()
+	if err != nil {
+		return err
+	}
+	if backend.richCopy != nil && backend.richPasteCmd != nil {
+		return verifyClipboardWith(backend.richPasteCmd, html)
+	}
+	return verifyClipboardWith(backend.pasteCmd, plain)
+}
+
+// verifyClipboardWith reads the clipboard with pasteCmd and compares its SHA-256 hash with
+// the one of expected.
+func verifyClipboardWith(pasteCmd []string, expected string) error {
+	got, err := runClipboardCommand(pasteCmd, "")
+	if err != nil {
+		return fmt.Errorf("verification read failed: %w", err)
+	}
+
+	// Windows converts line endings and PowerShell appends a final newline on output,
+	// so compare normalised text there.
+	if runtime.GOOS == "windows" {
+		expected = normalizeWindowsClipboard(expected)
+		got = normalizeWindowsClipboard(got)
+	}
+
+	wantSum, gotSum := sha256.Sum256([]byte(expected)), sha256.Sum256([]byte(got))
+	if wantSum != gotSum {
+		return fmt.Errorf("clipboard verification failed: wrote %d bytes (sha256 %x), read back %d bytes (sha256 %x)",
+			len(expected), wantSum[:6], len(got), gotSum[:6])
+	}
+	return nil
+}
+
+// normalizeWindowsClipboard converts CRLF to LF and drops trailing line breaks.
+func normalizeWindowsClipboard(text string) string {
+	return strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// runClipboardCommand executes a clipboard command (like pbcopy, xclip, clip.exe) with a
+// deadline, piping the provided text to its standard input and returning its standard output.
+func runClipboardCommand(argv []string, input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+	defer cancel()
+
+	name := filepath.Base(argv[0])
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	// exec copies Stdin in its own goroutine, which avoids deadlocks with large inputs
+	// and is interrupted together with the process when the deadline passes.
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// xclip forks a child that keeps serving the selection and may inherit our pipes.
+	// WaitDelay stops Wait from blocking on them once the command itself has exited.
+	cmd.WaitDelay = 500 * time.Millisecond
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("%s timed out after %s", name, clipboardTimeout)
+	}
+	// ErrWaitDelay means the command succeeded but a forked child still held the pipes.
+	if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s command failed: %w: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s command failed: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// --- Display Diagnostics ---
+
+// checkWaylandDisplay verifies that $WAYLAND_DISPLAY is set and that the compositor
+// socket accepts connections.
+func checkWaylandDisplay() error {
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		return errors.New("WAYLAND_DISPLAY is not set (no Wayland compositor available)")
+	}
+	socket := display
+	if !filepath.IsAbs(socket) {
+		runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+		if runtimeDir == "" {
+			return errors.New("XDG_RUNTIME_DIR is not set (needed to locate the Wayland socket)")
+		}
+		socket = filepath.Join(runtimeDir, display)
+	}
+	conn, err := net.DialTimeout("unix", socket, displayDialTimeout)
+	if err != nil {
+		return fmt.Errorf("Wayland display '%s' is not reachable: %w", display, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// checkX11Display verifies that $DISPLAY is set and that the X server behind it accepts
+// connections, so xclip/xsel fail fast instead of hanging.
+func checkX11Display() error {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return errors.New("DISPLAY is not set (no X server available)")
+	}
+
+	addresses, err := x11DisplayAddresses(display)
+	if err != nil {
+		return err
+	}
+	var dialErr error
+	for _, addr := range addresses {
+		conn, err := net.DialTimeout(addr.network, addr.address, displayDialTimeout)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		dialErr = err
+	}
+	return fmt.Errorf("X display '%s' is not reachable: %w", display, dialErr)
+}
+
+// displayAddress is a network endpoint a display server may listen on.
+type displayAddress struct {
+	network string // "unix" or "tcp".
+	address string
+}
+
+// x11DisplayAddresses converts a DISPLAY value ("[host]:display[.screen]") into the socket
+// addresses the X server may listen on, in the order they should be tried.
+func x11DisplayAddresses(display string) ([]displayAddress, error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return nil, fmt.Errorf("malformed DISPLAY '%s'", display)
+	}
+	host := display[:colon]
+	number := display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot] // Drop the screen number.
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("malformed DISPLAY '%s'", display)
+	}
+
+	switch {
+	case strings.HasPrefix(host, "/"):
+		// Full socket path (e.g., XQuartz's launchd socket on macOS).
+		return []displayAddress{{"unix", display}}, nil
+	case host == "" || host == "unix":
+		socket := fmt.Sprintf("/tmp/.X11-unix/X%d", n)
+		// Linux X servers usually also listen on the abstract socket, which works even
+		// when /tmp/.X11-unix is not shared (e.g., inside containers).
+		return []displayAddress{{"unix", socket}, {"unix", "@" + socket}}, nil
+	default:
+		return []displayAddress{{"tcp", net.JoinHostPort(host, strconv.Itoa(6000+n))}}, nil
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWindowsCFHTML(t *testing.T) {
+	headerRegexp := regexp.MustCompile(`^Version:0\.9\r\nStartHTML:(\d{10})\r\nEndHTML:(\d{10})\r\nStartFragment:(\d{10})\r\nEndFragment:(\d{10})\r\n`)
+
+	tests := []struct {
+		name         string
+		doc          string
+		wantFragment string
+	}{
+		{
+			name:         "document with a body",
+			doc:          "<html><head></head><body>\n<p>main.go</p>\n</body></html>\n",
+			wantFragment: "\n<p>main.go</p>\n",
+		},
+		{
+			name:         "multi-byte text",
+			doc:          "<html><body><p>Größe: 日本語</p></body></html>",
+			wantFragment: "<p>Größe: 日本語</p>",
+		},
+		{
+			name:         "fragment without a body",
+			doc:          "<pre>x &lt; y</pre>",
+			wantFragment: "<pre>x &lt; y</pre>",
+		},
+		{
+			name:         "body text quoting the closing tag",
+			doc:          "<html><body><code>&lt;/body&gt;</code></body></html>",
+			wantFragment: "<code>&lt;/body&gt;</code>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := windowsCFHTML(tt.doc)
+			match := headerRegexp.FindStringSubmatch(got)
+			if match == nil {
+				t.Fatalf("no CF_HTML header in %q", got)
+			}
+			offsets := make([]int, 4)
+			for i := range offsets {
+				offsets[i], _ = strconv.Atoi(match[i+1])
+			}
+			startHTML, endHTML, startFragment, endFragment := offsets[0], offsets[1], offsets[2], offsets[3]
+
+			// The offsets count bytes, not runes, and must point into the returned text.
+			if startHTML != len(match[0]) {
+				t.Errorf("StartHTML = %d, want the header length %d", startHTML, len(match[0]))
+			}
+			if endHTML != len(got) {
+				t.Errorf("EndHTML = %d, want the total length %d", endHTML, len(got))
+			}
+			if !(startHTML <= startFragment && startFragment <= endFragment && endFragment <= endHTML) {
+				t.Fatalf("offsets out of order: %v", offsets)
+			}
+			if fragment := got[startFragment:endFragment]; fragment != tt.wantFragment {
+				t.Errorf("fragment = %q, want %q", fragment, tt.wantFragment)
+			}
+			if got[startFragment-len("<!--StartFragment-->"):startFragment] != "<!--StartFragment-->" {
+				t.Errorf("StartFragment does not follow the start marker in %q", got)
+			}
+			if got[endFragment:endFragment+len("<!--EndFragment-->")] != "<!--EndFragment-->" {
+				t.Errorf("EndFragment does not precede the end marker in %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// --- User Configuration ---
+
+// userConfigFileName is the personal configuration file in the user's config directory. Unlike
+// presetsFileName it belongs to a person, not to a repository.
+const userConfigFileName = "config.toml"
+
+// userConfig holds the personal settings read from userConfigFileName:
+//
+//	theme = "light"
+//	previewStyle = "solarized-light"
+//	history = "full"               # Also keep the copied bytes in the copy history.
+//
+//	[colors]
+//	cursor = "#005fd7"
+//
+//	[keys]
+//	clearSelected = ["C"]          # Plain 'c' no longer clears everything.
+//	filterToggle = ["ctrl+x"]
+type userConfig struct {
+	Theme        string              `toml:"theme"`        // Built-in theme (see themes); "" for the default.
+	PreviewStyle string              `toml:"previewStyle"` // Chroma style of the preview, overriding the theme's.
+	Colors       map[string]string   `toml:"colors"`       // Colour overrides by role; see colorRoles.
+	Keys         map[string][]string `toml:"keys"`         // Key overrides by action name; see keyMap.actions.
+	History      string              `toml:"history"`      // Copy history mode used without -history; "" for historyModeMeta.
+}
+
+// userConfigPath returns the location of the user configuration file:
+// $XDG_CONFIG_HOME/yank/config.toml, or the platform's user config directory.
+func userConfigPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName, userConfigFileName), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(dir, appName, userConfigFileName), nil
+}
+
+// readUserConfig parses and validates a user configuration file, including the key overrides.
+func readUserConfig(path string) (userConfig, error) {
+	var config userConfig
+	meta, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return userConfig{}, fmt.Errorf("reading config '%s': %w", path, err)
+	}
+	// Unknown keys are most likely typos, so report them instead of silently ignoring a setting.
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return userConfig{}, fmt.Errorf("config '%s': unknown key '%s'", path, undecoded[0])
+	}
+	if _, err := resolveTheme(config.Theme, config.Colors, config.PreviewStyle); err != nil {
+		return userConfig{}, fmt.Errorf("config '%s': %w", path, err)
+	}
+	keys := defaultKeyMap()
+	if err := keys.apply(config.Keys); err != nil {
+		return userConfig{}, fmt.Errorf("config '%s': %w", path, err)
+	}
+	if config.History != "" {
+		if err := validateHistoryMode(config.History); err != nil {
+			return userConfig{}, fmt.Errorf("config '%s': %w", path, err)
+		}
+	}
+	return config, nil
+}
+
+// loadUserConfig reads the user configuration. A missing file yields the defaults.
+func loadUserConfig() (userConfig, error) {
+	path, err := userConfigPath()
+	if err != nil {
+		return userConfig{}, err
+	}
+	config, err := readUserConfig(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return userConfig{}, nil
+	}
+	return config, err
+}
+
+// theme returns the theme selected by the configuration. The settings were validated when
+// the file was read.
+func (c userConfig) theme() theme {
+	t, err := resolveTheme(c.Theme, c.Colors, c.PreviewStyle)
+	if err != nil {
+		t, _ = resolveTheme("", nil, "")
+	}
+	return t
+}
+
+// keyMap returns the default keybindings with the overrides of the configuration applied.
+// The overrides were validated when the file was read.
+func (c userConfig) keyMap() keyMap {
+	keys := defaultKeyMap()
+	if err := keys.apply(c.Keys); err != nil {
+		return defaultKeyMap()
+	}
+	return keys
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- File Details ---
+
+const (
+	detailsMaxCountBytes = 16 << 20 // Lines of larger files are not counted.
+	detailsBinaryProbe   = 8000     // Bytes checked for a NUL byte to detect binary files, like the preview.
+	detailsCountQueue    = 256      // Files waiting to be counted; more are requested again on the next render.
+	bytesPerToken        = 4        // Rough average for source code and English text.
+)
+
+// detailStyle renders the detail columns of a row (set by applyTheme).
+var detailStyle lipgloss.Style
+
+// fileStat is the metadata shown in detail rows and used for sorting. It is read lazily and
+// cached until the sort mode changes (see fileStatCache.invalidate).
+type fileStat struct {
+	size    int64
+	modTime time.Time
+	lines   int  // Number of lines; only valid if counted is set.
+	counted bool // Flag set once lines was determined (or found impossible, with lines = -1).
+	binary  bool // Flag set if counting found a binary file; it has no lines or tokens.
+}
+
+// lineCountMsg carries the line count of a file, counted in the background.
+type lineCountMsg struct {
+	path   string
+	lines  int
+	binary bool
+}
+
+// fileStatCache caches fileStat per relative path. It is shared by the model and the delegate,
+// and only used from the Bubble Tea goroutine; lines are counted by a goroutine of its own,
+// so rendering never waits for a file to be read.
+type fileStatCache struct {
+	targetDir string
+	stats     map[string]fileStat
+	pending   map[string]bool   // Files queued for counting.
+	requests  chan string       // Files to count, read by the counting goroutine.
+	results   chan lineCountMsg // Counted files, received by waitForLineCount.
+}
+
+// newFileStatCache creates an empty cache for the files below targetDir and starts the
+// goroutine counting lines.
+func newFileStatCache(targetDir string) *fileStatCache {
+	c := &fileStatCache{
+		targetDir: targetDir,
+		stats:     make(map[string]fileStat),
+		pending:   make(map[string]bool),
+		requests:  make(chan string, detailsCountQueue),
+		results:   make(chan lineCountMsg),
+	}
+	go func() {
+		for relativePath := range c.requests {
+			lines, binary := countFileLines(filepath.Join(targetDir, relativePath))
+			c.results <- lineCountMsg{path: relativePath, lines: lines, binary: binary}
+		}
+	}()
+	return c
+}
+
+// stat returns the size and modification time of a file. Unreadable files count as empty and old.
+func (c *fileStatCache) stat(relativePath string) fileStat {
+	if stat, ok := c.stats[relativePath]; ok {
+		return stat
+	}
+	var stat fileStat
+	if info, err := os.Stat(filepath.Join(c.targetDir, relativePath)); err == nil {
+		stat = fileStat{size: info.Size(), modTime: info.ModTime()}
+	}
+	c.stats[relativePath] = stat
+	return stat
+}
+
+// invalidate drops the cached metadata, so files edited during the session are read again
+// when they are sorted or shown next. Counts still in flight are stored when they arrive.
+func (c *fileStatCache) invalidate() {
+	clear(c.stats)
+}
+
+// withLines returns the metadata of a file including its line count if known. Otherwise the
+// file is queued for counting and the result arrives as a lineCountMsg.
+func (c *fileStatCache) withLines(relativePath string) fileStat {
+	stat := c.stat(relativePath)
+	if stat.counted || c.pending[relativePath] {
+		return stat
+	}
+	select {
+	case c.requests <- relativePath:
+		c.pending[relativePath] = true
+	default: // The queue is full; the file is requested again when the row is rendered next.
+	}
+	return stat
+}
+
+// setLines stores a line count received from the counting goroutine.
+func (c *fileStatCache) setLines(msg lineCountMsg) {
+	stat := c.stat(msg.path)
+	stat.lines, stat.binary, stat.counted = msg.lines, msg.binary, true
+	c.stats[msg.path] = stat
+	delete(c.pending, msg.path)
+}
+
+// waitForLineCount returns the command receiving the next line count.
+func waitForLineCount(results <-chan lineCountMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-results
+	}
+}
+
+// countFileLines counts the lines of a file, reading it in chunks. Files too large or unreadable
+// get lines = -1; binary files, detected by a NUL byte near the start, are reported as such.
+func countFileLines(absolutePath string) (lines int, binary bool) {
+	file, err := os.Open(absolutePath)
+	if err != nil {
+		return -1, false
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Size() > detailsMaxCountBytes {
+		return -1, false
+	}
+
+	buf := make([]byte, 32<<10)
+	read := 0       // Bytes read so far.
+	last := byte(0) // Last byte read, to count a final line without a newline.
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if read < detailsBinaryProbe && bytes.IndexByte(buf[:min(n, detailsBinaryProbe-read)], 0) >= 0 {
+				return -1, true
+			}
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			read += n
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return -1, false
+		}
+	}
+	if read > 0 && last != '\n' {
+		lines++
+	}
+	return lines, false
+}
+
+// estimateTokens gives a rough LLM token count for size bytes of text.
+func estimateTokens(size int64) int64 {
+	return (size + bytesPerToken - 1) / bytesPerToken
+}
+
+// formatRelativeTime describes t relative to now in a compact form ("3h ago").
+func formatRelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+	}
+}
+
+// formatCount shortens large counts ("12.3k").
+func formatCount(n int64) string {
+	if n < 10000 {
+		return fmt.Sprint(n)
+	}
+	if n < 1000000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%.1fM", float64(n)/1000000)
+}
+
+// detailColumns renders the right-aligned size, age, line and token columns of a row.
+// Lines and tokens show "…" while the file is being counted, and "-" for binary files.
+func detailColumns(stat fileStat, now time.Time) string {
+	lines, tokens := "…", "…"
+	switch {
+	case !stat.counted:
+	case stat.binary:
+		lines, tokens = "-", "-"
+	default:
+		lines, tokens = "-", "~"+formatCount(estimateTokens(stat.size))
+		if stat.lines >= 0 {
+			lines = formatCount(int64(stat.lines))
+		}
+	}
+	return fmt.Sprintf("%9s %9s %7s L %7s tok",
+		formatByteSize(int(stat.size)), formatRelativeTime(stat.modTime, now), lines, tokens)
+}
+
+// truncateMiddle shortens s to width cells by replacing its middle with an ellipsis, which keeps
+// both the top-level directory and the file name of a long path visible.
+func truncateMiddle(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	head, tail := truncateMiddleRunes(s, width)
+	if head+tail == len(runes) {
+		return s
+	}
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+// truncateMiddleRunes returns how many runes truncateMiddle keeps from the start and the end
+// of s; together they are all runes if s fits.
+func truncateMiddleRunes(s string, width int) (head, tail int) {
+	runes := []rune(s)
+	if lipgloss.Width(s) <= width {
+		return len(runes), 0
+	}
+	if width <= 0 {
+		return 0, 0
+	}
+	keep := width - 1
+	head = keep / 2
+	tail = keep - head
+	for head+tail > 0 && lipgloss.Width(string(runes[:head])+"…"+string(runes[len(runes)-tail:])) > width {
+		if tail > head {
+			tail--
+		} else {
+			head--
+		}
+	}
+	return head, tail
+}
+
+// truncateEnd shortens s to width cells, ending it with an ellipsis.
+func truncateEnd(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// setShowDetails switches the detail columns of the rows on or off.
+func (m *model) setShowDetails(show bool) {
+	m.showDetails = show
+	m.updateDelegate()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestCountFileLines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		content    string
+		wantLines  int
+		wantBinary bool
+	}{
+		{name: "empty", content: "", wantLines: 0},
+		{name: "one line", content: "package main\n", wantLines: 1},
+		{name: "no final newline", content: "a\nb", wantLines: 2},
+		{name: "blank lines", content: "\n\n\n", wantLines: 3},
+		{name: "crlf", content: "a\r\nb\r\n", wantLines: 2},
+		{name: "longer than a read", content: strings.Repeat("line\n", 20000), wantLines: 20000},
+		{name: "nul byte", content: "text\x00more\n", wantLines: -1, wantBinary: true},
+		{name: "nul byte after the probe", content: strings.Repeat("x", detailsBinaryProbe) + "\x00\n", wantLines: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			lines, binary := countFileLines(path)
+			if lines != tt.wantLines || binary != tt.wantBinary {
+				t.Errorf("countFileLines() = %d, %v; want %d, %v", lines, binary, tt.wantLines, tt.wantBinary)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if lines, binary := countFileLines(filepath.Join(dir, "missing")); lines != -1 || binary {
+			t.Errorf("countFileLines() = %d, %v; want -1, false", lines, binary)
+		}
+	})
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "main.go", width: 10, want: "main.go"},
+		{s: "main.go", width: 7, want: "main.go"},
+		{s: "main.go", width: 6, want: "ma….go"},
+		{s: "internal/api/handler.go", width: 10, want: "inte…er.go"},
+		{s: "internal/api/handler.go", width: 2, want: "…o"},
+		{s: "internal/api/handler.go", width: 1, want: "…"},
+		{s: "internal/api/handler.go", width: 0, want: ""},
+		{s: "日本語ファイル.go", width: 8, want: "日本….go"},
+		{s: "docs/überblick.md", width: 9, want: "docs…k.md"},
+	}
+	for _, tt := range tests {
+		got := truncateMiddle(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("truncateMiddle(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if w := lipgloss.Width(got); w > max(tt.width, 0) {
+			t.Errorf("truncateMiddle(%q, %d) is %d cells wide", tt.s, tt.width, w)
+		}
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2025, 5, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{t: time.Time{}, want: "-"},
+		{t: now.Add(-30 * time.Second), want: "just now"},
+		{t: now.Add(-5 * time.Minute), want: "5m ago"},
+		{t: now.Add(-3 * time.Hour), want: "3h ago"},
+		{t: now.Add(-4 * 24 * time.Hour), want: "4d ago"},
+		{t: now.Add(-65 * 24 * time.Hour), want: "2mo ago"},
+		{t: now.Add(-800 * 24 * time.Hour), want: "2y ago"},
+	}
+	for _, tt := range tests {
+		if got := formatRelativeTime(tt.t, now); got != tt.want {
+			t.Errorf("formatRelativeTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 9999, want: "9999"},
+		{n: 12345, want: "12.3k"},
+		{n: 2500000, want: "2.5M"},
+	}
+	for _, tt := range tests {
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("formatCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+	"github.com/xo/terminfo"
+)
+
+// --- Doctor: Environment Diagnostics ---
+
+// doctorReport prints the diagnostic sections with consistent status markers.
+type doctorReport struct {
+	failures int // Number of failed checks; determines the exit code.
+}
+
+// section prints a section heading.
+func (r *doctorReport) section(title string) {
+	fmt.Println()
+	fmt.Println(titleStyle.Render(title))
+}
+
+// ok prints a passing check.
+func (r *doctorReport) ok(label, detail string) {
+	r.line(checkedStyle.Render("ok  "), label, detail)
+}
+
+// warn prints a check that is not fatal but may limit functionality.
+func (r *doctorReport) warn(label, detail string) {
+	r.line(filterPromptStyle.Render("warn"), label, detail)
+}
+
+// fail prints a failing check and counts it.
+func (r *doctorReport) fail(label, detail string) {
+	r.failures++
+	r.line(errorStyle.Render("fail"), label, detail)
+}
+
+// info prints a neutral informational line.
+func (r *doctorReport) info(label, detail string) {
+	r.line(helpStyle.Render("    "), label, detail)
+}
+
+// line prints a single aligned report line.
+func (r *doctorReport) line(marker, label, detail string) {
+	fmt.Printf("  %s %-22s %s\n", marker, label, detail)
+}
+
+// runDoctor implements the "doctor" subcommand, printing clipboard, terminal,
+// configuration, and persistence diagnostics. It returns the process exit code.
+func runDoctor(args []string) int {
+	fset := flag.NewFlagSet(appName+" doctor", flag.ContinueOnError)
+	dir := fset.String("dir", ".", "Directory whose selection state should be inspected")
+	stateFlag := fset.String("state", defaultStateMode(), "Selection state storage mode to inspect: tree, xdg or xdg-git")
+	noRoundTrip := fset.Bool("no-roundtrip", false, "Skip the clipboard write/read test (leaves the clipboard untouched)")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage:\n  %s doctor [-dir <directory>] [-state tree|xdg|xdg-git] [-no-roundtrip]\n\nOptions:\n", appName)
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	targetDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path '%s': %v\n", *dir, err)
+		return 1
+	}
+	if err := validateStateMode(*stateFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	stateMode = *stateFlag
+
+	r := &doctorReport{}
+	fmt.Printf("%s doctor (%s/%s, %s)\n", appName, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	r.section("Environment")
+	for _, name := range []string{"DISPLAY", "WAYLAND_DISPLAY", "XDG_SESSION_TYPE", "TERM", "COLORTERM", "TERM_PROGRAM", "TMUX", "STY", "SSH_TTY", "NO_COLOR", "YANK_STATE", "XDG_STATE_HOME"} {
+		if value, set := os.LookupEnv(name); set {
+			r.info(name, strconv.Quote(value))
+		} else {
+			r.info(name, helpStyle.Render("(unset)"))
+		}
+	}
+
+	doctorClipboard(r, !*noRoundTrip)
+	doctorTerminal(r)
+	doctorConfig(r, targetDir)
+	doctorPersistence(r, targetDir)
+
+	fmt.Println()
+	if r.failures > 0 {
+		fmt.Println(errorStyle.Render(fmt.Sprintf("%d check(s) failed.", r.failures)))
+		return 1
+	}
+	fmt.Println(checkedStyle.Render("All checks passed."))
+	return 0
+}
+
+// doctorClipboard reports every candidate clipboard backend and, optionally, performs a
+// write/read round-trip with the backend yank would actually use.
+func doctorClipboard(r *doctorReport, roundTrip bool) {
+	r.section("Clipboard")
+
+	backends := clipboardBackends()
+	if len(backends) == 0 {
+		r.fail("backend", fmt.Sprintf("clipboard OS unsupported: %s", runtime.GOOS))
+		return
+	}
+	for _, b := range backends {
+		path, err := exec.LookPath(b.copyCmd[0])
+		if err != nil {
+			r.info(b.name, helpStyle.Render("not installed"))
+			continue
+		}
+		if b.requires != nil {
+			if reqErr := b.requires(); reqErr != nil {
+				r.warn(b.name, fmt.Sprintf("%s (%v)", path, reqErr))
+				continue
+			}
+		}
+		r.ok(b.name, path)
+	}
+
+	backend, err := checkClipboard()
+	if err != nil {
+		r.fail("active backend", err.Error())
+		return
+	}
+	r.ok("active backend", backend.name)
+
+	if !roundTrip {
+		r.info("round-trip", "skipped (-no-roundtrip)")
+		return
+	}
+
+	// Preserve the user's clipboard around the test when it can be read.
+	previous, prevErr := readFromClipboard()
+	marker := fmt.Sprintf("%s doctor round-trip %d", appName, time.Now().UnixNano())
+	start := time.Now()
+	if err := copyToClipboard(marker); err != nil {
+		r.fail("round-trip", fmt.Sprintf("write failed: %v", err))
+		return
+	}
+	got, err := readFromClipboard()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case err != nil:
+		r.fail("round-trip", fmt.Sprintf("read failed: %v", err))
+	case strings.TrimRight(got, "\r\n") != marker:
+		r.fail("round-trip", fmt.Sprintf("read back %d bytes, expected %d", len(got), len(marker)))
+	default:
+		r.ok("round-trip", fmt.Sprintf("write+read in %s", elapsed))
+	}
+	if prevErr == nil {
+		if err := copyToClipboard(previous); err != nil {
+			r.warn("restore", fmt.Sprintf("could not restore previous clipboard: %v", err))
+		}
+	}
+}
+
+// doctorTerminal reports colour support, OSC 52 likelihood, and alt-screen support.
+func doctorTerminal(r *doctorReport) {
+	r.section("Terminal")
+
+	stdoutTTY := isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+	stdinTTY := isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+	if stdoutTTY && stdinTTY {
+		r.ok("tty", "stdin and stdout are terminals")
+	} else {
+		r.warn("tty", fmt.Sprintf("stdin tty=%t, stdout tty=%t (the TUI needs a terminal)", stdinTTY, stdoutTTY))
+	}
+
+	profile := termenv.NewOutput(os.Stdout).EnvColorProfile()
+	switch profile {
+	case termenv.TrueColor, termenv.ANSI256:
+		r.ok("colour", profile.Name())
+	case termenv.ANSI:
+		r.warn("colour", "ANSI (16 colours only)")
+	default:
+		r.warn("colour", "none (Ascii profile)")
+	}
+	if dark := lipgloss.HasDarkBackground(); dark {
+		r.info("background", "dark")
+	} else {
+		r.info("background", "light")
+	}
+
+	// OSC 52 support cannot be queried reliably, so report what the environment suggests.
+	osc52, why := osc52Likely()
+	if osc52 {
+		r.ok("osc 52", why)
+	} else {
+		r.warn("osc 52", why)
+	}
+
+	ti, err := terminfo.LoadFromEnv()
+	switch {
+	case err != nil:
+		r.warn("alt-screen", fmt.Sprintf("no terminfo entry: %v", err))
+	case len(ti.Strings[terminfo.EnterCaMode]) > 0:
+		r.ok("alt-screen", fmt.Sprintf("supported (%s)", ti.Names[0]))
+	default:
+		r.warn("alt-screen", fmt.Sprintf("terminfo '%s' has no smcup capability", ti.Names[0]))
+	}
+}
+
+// osc52Likely guesses whether the terminal forwards OSC 52 clipboard escapes,
+// based on well-known terminal identifiers.
+func osc52Likely() (bool, string) {
+	termProgram := os.Getenv("TERM_PROGRAM")
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("TMUX") != "":
+		return true, "inside tmux (requires 'set -g set-clipboard on')"
+	case os.Getenv("STY") != "":
+		return false, "inside GNU screen (OSC 52 is usually dropped)"
+	case termProgram == "iTerm.app", termProgram == "WezTerm", termProgram == "vscode":
+		return true, fmt.Sprintf("TERM_PROGRAM=%s", termProgram)
+	case termProgram == "Apple_Terminal":
+		return false, "Apple Terminal does not support OSC 52"
+	case strings.Contains(term, "kitty"), strings.Contains(term, "alacritty"),
+		strings.Contains(term, "foot"), strings.Contains(term, "ghostty"), strings.HasPrefix(term, "xterm"):
+		return true, fmt.Sprintf("TERM=%s usually supports it", term)
+	}
+	return false, fmt.Sprintf("unknown for TERM=%q", term)
+}
+
+// doctorConfig lists the configuration files yank reads and whether they exist.
+func doctorConfig(r *doctorReport, targetDir string) {
+	r.section("Configuration")
+
+	locations := configLocations(targetDir)
+	if len(locations) == 0 {
+		r.info("config files", "none are read by this version")
+		return
+	}
+	for _, loc := range locations {
+		if _, err := os.Stat(loc.path); err == nil {
+			if loc.validate == nil {
+				r.ok(loc.label, loc.path)
+			} else if err := loc.validate(loc.path); err != nil {
+				r.fail(loc.label, err.Error())
+			} else {
+				r.ok(loc.label, loc.path)
+			}
+		} else if errors.Is(err, fs.ErrNotExist) {
+			r.info(loc.label, loc.path+helpStyle.Render(" (not found)"))
+		} else {
+			r.fail(loc.label, fmt.Sprintf("%s: %v", loc.path, err))
+		}
+	}
+}
+
+// configLocation is a configuration file yank may read.
+type configLocation struct {
+	label    string
+	path     string
+	validate func(path string) error // Optional check of an existing file's contents.
+}
+
+// configLocations returns the configuration files yank reads for targetDir.
+func configLocations(targetDir string) []configLocation {
+	presetsPath := findPresetsFile(targetDir)
+	if presetsPath == "" {
+		presetsPath = filepath.Join(targetDir, presetsFileName)
+	}
+	locations := []configLocation{
+		{
+			label: "team presets",
+			path:  presetsPath,
+			validate: func(path string) error {
+				_, err := readPresetsFile(path)
+				return err
+			},
+		},
+	}
+	if configPath, err := userConfigPath(); err == nil {
+		locations = append(locations, configLocation{
+			label: "user config",
+			path:  configPath,
+			validate: func(path string) error {
+				_, err := readUserConfig(path)
+				return err
+			},
+		})
+	}
+	return locations
+}
+
+// doctorPersistence reports the state of the persistence file of targetDir.
+func doctorPersistence(r *doctorReport, targetDir string) {
+	r.section("Selection state")
+
+	r.info("directory", targetDir)
+	r.info("storage mode", stateMode)
+	filePath := getPersistenceFilePath(targetDir)
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		r.info("state file", filePath+helpStyle.Render(" (not present, no saved selection)"))
+		if stateMode != stateModeTree {
+			if _, treeErr := os.Stat(treeStateFilePath(targetDir)); treeErr == nil {
+				r.warn("in-tree file", treeStateFilePath(targetDir)+" (migrated on next start)")
+			}
+		}
+		return
+	}
+	if err != nil {
+		r.fail("state file", err.Error())
+		return
+	}
+	state, err := readSelectionState(targetDir)
+	if err != nil {
+		r.fail("state file", err.Error())
+		return
+	}
+	r.ok("state file", fmt.Sprintf("%s (%d bytes, modified %s)", filePath, info.Size(), info.ModTime().Format("2006-01-02 15:04:05")))
+	if state.legacy {
+		r.info("format", "legacy plain-line (rewritten as JSON on next save)")
+	} else {
+		r.info("format", fmt.Sprintf("JSON, version %d", stateFormatVersion))
+	}
+
+	// Check every saved entry against the file system.
+	doctorSelectionEntries(r, targetDir, "current selection", state.current)
+	for _, pattern := range state.patterns {
+		if err := validatePattern(pattern); err != nil {
+			r.warn("pattern", err.Error())
+		} else {
+			r.info("pattern", pattern)
+		}
+	}
+	for _, name := range state.setNames() {
+		doctorSelectionEntries(r, targetDir, "set "+strconv.Quote(name), state.sets[name])
+	}
+}
+
+// doctorSelectionEntries reports how many of the given saved paths still exist.
+func doctorSelectionEntries(r *doctorReport, targetDir, label string, paths []string) {
+	missing := 0
+	for _, relativePath := range paths {
+		if _, statErr := os.Stat(filepath.Join(targetDir, relativePath)); statErr != nil {
+			missing++
+			r.warn("stale entry", fmt.Sprintf("%s (%s)", relativePath, label))
+		}
+	}
+	if missing == 0 {
+		r.ok(label, fmt.Sprintf("%d entries, all present", len(paths)))
+	} else {
+		r.warn(label, fmt.Sprintf("%d entries, %d missing", len(paths), missing))
+	}
+}
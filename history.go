@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Copy History ---
+
+// History modes, selected with -history.
+const (
+	historyModeFull = "full" // Record metadata and the copied bytes (opt-in: the bytes may hold secrets).
+	historyModeMeta = "meta" // Record metadata only (time, directory, files, hash; default).
+	historyModeOff  = "off"  // Do not record anything.
+)
+
+const (
+	historyMaxEntries      = 50      // Oldest entries beyond this count are pruned.
+	historyMaxContentBytes = 8 << 20 // Larger bundles are recorded without their content.
+)
+
+// historyEntry describes one past copy. Entries are stored one JSON object per line in
+// the history index; the copied bytes, if kept, live in a separate file per entry.
+type historyEntry struct {
+	ID         int       `json:"id"`                   // Increasing number, used to refer to the entry.
+	Time       time.Time `json:"time"`                 // When the copy happened.
+	Dir        string    `json:"dir"`                  // Absolute path of the scanned directory.
+	Files      []string  `json:"files"`                // Relative paths of the copied files, in bundle order.
+	Bytes      int       `json:"bytes"`                // Size of the plain text bundle.
+	SHA256     string    `json:"sha256"`               // Hash of the plain text bundle.
+	HTML       bool      `json:"html,omitempty"`       // Whether a text/html rendering was copied as well.
+	HasContent bool      `json:"hasContent,omitempty"` // Whether the bundle bytes were kept.
+}
+
+// validateHistoryMode checks a user-supplied history mode.
+func validateHistoryMode(mode string) error {
+	switch mode {
+	case historyModeFull, historyModeMeta, historyModeOff:
+		return nil
+	}
+	return fmt.Errorf("unknown history mode '%s' (use %s, %s or %s)", mode, historyModeFull, historyModeMeta, historyModeOff)
+}
+
+// historyDir returns the directory holding the copy history. It is shared by all
+// directories and independent of the selection state storage mode.
+func historyDir() (string, error) {
+	dir, err := xdgStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// historyIndexPath returns the path of the history index inside dir.
+func historyIndexPath(dir string) string {
+	return filepath.Join(dir, "history.jsonl")
+}
+
+// historyContentPath returns the path holding the bundle bytes of entry id inside dir.
+func historyContentPath(dir string, id int) string {
+	return filepath.Join(dir, strconv.Itoa(id)+".txt")
+}
+
+// readHistory returns all history entries, oldest first. A missing history is empty;
+// malformed lines are skipped.
+func readHistory() ([]historyEntry, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return nil, err
+	}
+	return readHistoryIndex(historyIndexPath(dir))
+}
+
+// readHistoryIndex parses the history index at indexPath (see readHistory).
+func readHistoryIndex(indexPath string) ([]historyEntry, error) {
+	content, err := os.ReadFile(indexPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history '%s': %w", indexPath, err)
+	}
+	var entries []historyEntry
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 16<<20) // File lists of large selections make long lines.
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && entry.ID > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// recordHistory appends a copy to the history, keeping the bundle bytes if storeContent is
+// set and the bundle is not too large, and prunes entries beyond historyMaxEntries.
+func recordHistory(targetDir string, files []string, content string, html bool, storeContent bool) (historyEntry, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return historyEntry{}, err
+	}
+	indexPath := historyIndexPath(dir)
+	lock, err := lockStateFile(indexPath)
+	if err != nil {
+		return historyEntry{}, err
+	}
+	defer lock.unlock()
+
+	entries, err := readHistoryIndex(indexPath)
+	if err != nil {
+		return historyEntry{}, err
+	}
+	sum := sha256.Sum256([]byte(content))
+	entry := historyEntry{
+		ID:     1,
+		Time:   time.Now(),
+		Dir:    targetDir,
+		Files:  files,
+		Bytes:  len(content),
+		SHA256: hex.EncodeToString(sum[:]),
+		HTML:   html,
+	}
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	if storeContent && len(content) <= historyMaxContentBytes {
+		if err := writeFileAtomic(historyContentPath(dir, entry.ID), []byte(content), 0600); err != nil {
+			return entry, fmt.Errorf("failed write history content: %w", err)
+		}
+		entry.HasContent = true
+	}
+
+	entries = append(entries, entry)
+	if excess := len(entries) - historyMaxEntries; excess > 0 {
+		for _, pruned := range entries[:excess] {
+			_ = os.Remove(historyContentPath(dir, pruned.ID))
+		}
+		entries = entries[excess:]
+	}
+	return entry, writeHistoryIndex(indexPath, entries)
+}
+
+// writeHistoryIndex writes entries to the history index, one JSON object per line.
+func writeHistoryIndex(indexPath string, entries []historyEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	// Bundles may contain secrets, so the history is private to the user.
+	if err := writeFileAtomic(indexPath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed write history '%s': %w", indexPath, err)
+	}
+	return nil
+}
+
+// readHistoryContent returns the exact bytes copied for entry, verifying their hash.
+func readHistoryContent(entry historyEntry) (string, error) {
+	if !entry.HasContent {
+		return "", fmt.Errorf("history entry #%d was recorded without its content", entry.ID)
+	}
+	dir, err := historyDir()
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(historyContentPath(dir, entry.ID))
+	if err != nil {
+		return "", fmt.Errorf("reading content of history entry #%d: %w", entry.ID, err)
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return "", fmt.Errorf("content of history entry #%d does not match its recorded hash", entry.ID)
+	}
+	return string(content), nil
+}
+
+// findHistoryEntry returns the entry with the given ID.
+func findHistoryEntry(entries []historyEntry, id int) (historyEntry, error) {
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return historyEntry{}, fmt.Errorf("history entry #%d not found", id)
+}
+
+// clearHistory removes the whole history, including stored content.
+func clearHistory() error {
+	dir, err := historyDir()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing history '%s': %w", dir, err)
+	}
+	return nil
+}
+
+// formatByteSize renders a byte count for humans (e.g., "12.3 KB").
+func formatByteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// --- History Subcommand ---
+
+// runHistory implements the "history" subcommand. It returns the process exit code.
+//
+//	yank history [list] [-dir <directory>] [-n <count>]
+//	yank history show|cat|copy|restore <id>
+//	yank history clear
+func runHistory(args []string) int {
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fset := flag.NewFlagSet(appName+" history", flag.ContinueOnError)
+	dir := fset.String("dir", "", "Only list copies made in this directory")
+	limit := fset.Int("n", 20, "Maximum number of entries to list (0 for all)")
+	stateFlag := fset.String("state", defaultStateMode(), "Selection state storage mode used by restore: tree, xdg or xdg-git")
+	fset.Usage = func() {
+		out := fset.Output()
+		fmt.Fprintf(out, "Usage:\n")
+		fmt.Fprintf(out, "  %s history [list] [-dir <directory>] [-n <count>]   List past copies, newest first.\n", appName)
+		fmt.Fprintf(out, "  %s history show <id>      Show details and the file list of an entry.\n", appName)
+		fmt.Fprintf(out, "  %s history cat <id>       Print the exact copied bytes to stdout.\n", appName)
+		fmt.Fprintf(out, "  %s history copy <id>      Copy the exact bytes to the clipboard again.\n", appName)
+		fmt.Fprintf(out, "  %s history restore <id>   Make the entry's files the saved selection of its directory.\n", appName)
+		fmt.Fprintf(out, "  %s history clear          Delete the whole history.\n", appName)
+		fmt.Fprintf(out, "\nOptions:\n")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := validateStateMode(*stateFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	stateMode = *stateFlag
+
+	entries, err := readHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch action {
+	case "list":
+		filterDir := ""
+		if *dir != "" {
+			if filterDir, err = filepath.Abs(*dir); err != nil {
+				fmt.Fprintf(os.Stderr, "Error resolving directory path '%s': %v\n", *dir, err)
+				return 1
+			}
+		}
+		printHistoryList(entries, filterDir, *limit)
+		return 0
+
+	case "clear":
+		if err := clearHistory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Removed %d history entries.\n", len(entries))
+		return 0
+
+	case "show", "cat", "copy", "restore":
+		if fset.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "Error: %s needs exactly one entry id\n", action)
+			return 2
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fset.Arg(0), "#"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid entry id '%s'\n", fset.Arg(0))
+			return 2
+		}
+		entry, err := findHistoryEntry(entries, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := runHistoryEntryAction(action, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown history action '%s'\n\n", action)
+	fset.Usage()
+	return 2
+}
+
+// runHistoryEntryAction performs show, cat, copy, or restore on a single entry.
+func runHistoryEntryAction(action string, entry historyEntry) error {
+	switch action {
+	case "show":
+		fmt.Printf("%s #%d\n", titleStyle.Render("History entry"), entry.ID)
+		fmt.Printf("  Time:      %s\n", entry.Time.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Directory: %s\n", entry.Dir)
+		fmt.Printf("  Size:      %s (%d bytes)\n", formatByteSize(entry.Bytes), entry.Bytes)
+		fmt.Printf("  SHA-256:   %s\n", entry.SHA256)
+		fmt.Printf("  HTML:      %t\n", entry.HTML)
+		if entry.HasContent {
+			fmt.Println("  Content:   stored")
+		} else {
+			fmt.Println("  Content:   not stored")
+		}
+		fmt.Printf("  Files (%d):\n", len(entry.Files))
+		for _, relativePath := range entry.Files {
+			fmt.Printf("    %s\n", relativePath)
+		}
+
+	case "cat":
+		content, err := readHistoryContent(entry)
+		if err != nil {
+			return err
+		}
+		fmt.Print(content)
+
+	case "copy":
+		content, err := readHistoryContent(entry)
+		if err != nil {
+			return err
+		}
+		if err := copyToClipboard(content); err != nil {
+			return fmt.Errorf("clipboard: %w", err)
+		}
+		fmt.Printf("Copied history entry #%d (%s, %d files) to the clipboard.\n", entry.ID, formatByteSize(entry.Bytes), len(entry.Files))
+
+	case "restore":
+		if _, err := os.Stat(entry.Dir); err != nil {
+			return fmt.Errorf("directory of history entry #%d: %w", entry.ID, err)
+		}
+		files := entry.Files
+		_, err := updateSelectionState(entry.Dir, func(state *selectionState) error {
+			// The entry lists every copied file, so patterns are dropped to restore it exactly.
+			// The loaded set or preset no longer applies either: the next start would bring the
+			// set back and overwrite it with these files, and copies would get the preamble.
+			state.current = files
+			state.patterns = nil
+			state.session.ActiveSet = ""
+			state.session.ActivePreset = ""
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored the selection of history entry #%d (%d files) in %s.\n", entry.ID, len(files), entry.Dir)
+	}
+	return nil
+}
+
+// printHistoryList prints entries newest first, optionally limited to one directory.
+func printHistoryList(entries []historyEntry, filterDir string, limit int) {
+	printed := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if filterDir != "" && entry.Dir != filterDir {
+			continue
+		}
+		if limit > 0 && printed == limit {
+			break
+		}
+		marker := " "
+		if !entry.HasContent {
+			marker = helpStyle.Render("-") // Metadata only; cannot be re-copied.
+		}
+		fmt.Printf("%s %s %s  %-9s %3d file(s)  %s\n",
+			titleStyle.Render(fmt.Sprintf("#%-4d", entry.ID)),
+			entry.Time.Format("2006-01-02 15:04"),
+			marker,
+			formatByteSize(entry.Bytes),
+			len(entry.Files),
+			entry.Dir,
+		)
+		printed++
+	}
+	if printed == 0 {
+		fmt.Println(helpStyle.Render("No copies recorded yet."))
+	}
+}
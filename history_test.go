@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRestoreHistoryEntry(t *testing.T) {
+	dir := t.TempDir()
+	state := newSelectionState()
+	state.current = []string{"api/a.go"}
+	state.patterns = []string{"api/**"}
+	state.sets["api"] = []string{"api/a.go", "api/b.go"}
+	state.session = sessionState{ActiveSet: "api", ActivePreset: "review", SortMode: sortModeSize}
+	if err := writeSelectionState(dir, state); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := historyEntry{ID: 3, Dir: dir, Files: []string{"main.go", "go.mod"}}
+	if err := runHistoryEntryAction("restore", entry); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	got, err := readSelectionState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go.mod", "main.go"}; !slices.Equal(got.current, want) {
+		t.Errorf("current = %q, want %q", got.current, want)
+	}
+	if len(got.patterns) != 0 {
+		t.Errorf("patterns = %q, want none", got.patterns)
+	}
+	if got.session.ActiveSet != "" || got.session.ActivePreset != "" {
+		t.Errorf("session still names set %q and preset %q", got.session.ActiveSet, got.session.ActivePreset)
+	}
+	if got.session.SortMode != sortModeSize {
+		t.Errorf("sort mode = %q, want the unrelated session state kept", got.session.SortMode)
+	}
+	if want := []string{"api/a.go", "api/b.go"}; !slices.Equal(got.sets["api"], want) {
+		t.Errorf("set api = %q, want it untouched %q", got.sets["api"], want)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- History Panel ---
+
+// historyPanel holds the overlay listing past copies of the scanned directory.
+type historyPanel struct {
+	active   bool           // Flag indicating whether the panel replaces the file list.
+	entries  []historyEntry // Copies made in the target directory, newest first.
+	cursor   int            // Index of the focused entry.
+	expanded bool           // Flag set while the file list of the focused entry is shown.
+}
+
+// historyCopiedMsg reports the result of re-copying a history entry.
+type historyCopiedMsg struct {
+	entry historyEntry
+	err   error
+}
+
+// openHistoryPanel loads the history of the target directory and shows the panel.
+func (m *model) openHistoryPanel() {
+	entries, err := readHistory()
+	if err != nil {
+		m.lastErr = err
+		return
+	}
+	panel := historyPanel{active: true}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Dir == m.targetDir {
+			panel.entries = append(panel.entries, entries[i])
+		}
+	}
+	m.history = panel
+}
+
+// focusedHistoryEntry returns the entry under the cursor.
+func (m *model) focusedHistoryEntry() (historyEntry, bool) {
+	if m.history.cursor < 0 || m.history.cursor >= len(m.history.entries) {
+		return historyEntry{}, false
+	}
+	return m.history.entries[m.history.cursor], true
+}
+
+// restoreHistorySelection replaces the selection (and patterns) with the files of entry.
+// The loaded set or preset no longer applies, so its preamble is not copied either.
+// Like other selection changes, it is saved with the next copy.
+func (m *model) restoreHistorySelection(entry historyEntry) tea.Cmd {
+	existing := m.existingPaths(entry.Files)
+	before := m.snapshotSelection()
+	clear(m.selected)
+	for _, relativePath := range existing {
+		m.selected[relativePath] = true
+	}
+	m.patterns = nil
+	m.activeSet = ""
+	m.activePreset = ""
+	m.recomputePatternMatches()
+	m.recordUndo(fmt.Sprintf("restore history #%d", entry.ID), before)
+
+	status := fmt.Sprintf("Restored the selection of #%d (%d files)", entry.ID, len(existing))
+	if missing := len(entry.Files) - len(existing); missing > 0 {
+		status += fmt.Sprintf(", %d missing", missing)
+	}
+	return m.setStatus(status)
+}
+
+// recopyHistoryCmd copies the stored bytes of entry to the clipboard in the background.
+func recopyHistoryCmd(entry historyEntry) tea.Cmd {
+	return func() tea.Msg {
+		content, err := readHistoryContent(entry)
+		if err == nil {
+			err = copyToClipboard(content)
+		}
+		return historyCopiedMsg{entry: entry, err: err}
+	}
+}
+
+// updateHistoryPanel handles key presses while the history panel is open.
+func (m model) updateHistoryPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.HistoryClose):
+		m.history.active = false
+
+	case key.Matches(msg, m.list.KeyMap.CursorUp):
+		if m.history.cursor > 0 {
+			m.history.cursor--
+		}
+
+	case key.Matches(msg, m.list.KeyMap.CursorDown):
+		if m.history.cursor < len(m.history.entries)-1 {
+			m.history.cursor++
+		}
+
+	case key.Matches(msg, m.keys.HistoryDetails):
+		m.history.expanded = !m.history.expanded
+
+	case key.Matches(msg, m.keys.HistoryRestore):
+		if entry, ok := m.focusedHistoryEntry(); ok {
+			m.lastErr = nil
+			m.history.active = false
+			return m, m.restoreHistorySelection(entry)
+		}
+
+	case key.Matches(msg, m.keys.HistoryRecopy):
+		if entry, ok := m.focusedHistoryEntry(); ok {
+			m.lastErr = nil
+			return m, recopyHistoryCmd(entry)
+		}
+	}
+	return m, nil
+}
+
+// viewHistoryPanel renders the history panel in place of the file list.
+func (m model) viewHistoryPanel() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Copy history:") + "\n\n")
+
+	if len(m.history.entries) == 0 {
+		sb.WriteString(helpStyle.Render("No copies recorded for this directory yet.") + "\n")
+	}
+	// Keep the focused entry visible on small terminals.
+	visible := max(m.list.Height()-6, 3)
+	start := max(0, m.history.cursor-visible+1)
+	for i := start; i < len(m.history.entries) && i < start+visible; i++ {
+		entry := m.history.entries[i]
+		line := fmt.Sprintf("#%-4d %s  %-9s %3d file(s)", entry.ID, entry.Time.Format("2006-01-02 15:04"), formatByteSize(entry.Bytes), len(entry.Files))
+		if !entry.HasContent {
+			line += "  (no content)"
+		}
+		if i == m.history.cursor {
+			sb.WriteString(selectedStyle.Render("> "+line) + "\n")
+			if m.history.expanded {
+				for _, relativePath := range entry.Files {
+					sb.WriteString(helpStyle.Render("      "+relativePath) + "\n")
+				}
+			}
+		} else {
+			sb.WriteString(itemStyle.Render("  "+line) + "\n")
+		}
+	}
+	sb.WriteString("\n" + helpStyle.Render(keyHints(m.keys.HistoryDetails, m.keys.HistoryRestore, m.keys.HistoryRecopy, m.keys.HistoryClose)))
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	} else if m.statusMessage != "" {
+		sb.WriteString("\n" + helpStyle.Render(m.statusMessage))
+	}
+	return docStyle.Render(sb.String())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// --- HTML Rendering ---
+
+// htmlStyleName is the chroma style used for the rich clipboard rendering. A light style
+// is used because docs tools and email clients almost always render on white.
+const htmlStyleName = "github"
+
+// htmlBundleBuilder accumulates a syntax-highlighted HTML rendering of a bundle,
+// mirroring the plain-text headers so both clipboard targets carry the same information.
+type htmlBundleBuilder struct {
+	sb        strings.Builder
+	style     *chroma.Style
+	formatter *chromahtml.Formatter
+}
+
+// newHTMLBundleBuilder creates a builder with inline styles (no CSS classes), since
+// pasted HTML fragments cannot rely on an external stylesheet.
+func newHTMLBundleBuilder() *htmlBundleBuilder {
+	b := &htmlBundleBuilder{
+		style:     styles.Get(htmlStyleName),
+		formatter: chromahtml.New(chromahtml.WithClasses(false), chromahtml.TabWidth(4)),
+	}
+	b.sb.WriteString("<html><head><meta charset=\"utf-8\"></head><body>\n")
+	return b
+}
+
+// addPreamble appends free text placed before the files, such as a preset's prompt preamble.
+func (b *htmlBundleBuilder) addPreamble(text string) {
+	fmt.Fprintf(&b.sb, "<p>%s</p>\n", strings.ReplaceAll(html.EscapeString(text), "\n", "<br>\n"))
+}
+
+// addFile appends one file: its header line followed by the highlighted content.
+// If highlighting fails, the content is emitted as an escaped <pre> block instead.
+func (b *htmlBundleBuilder) addFile(relativePath, header string, content []byte) {
+	fmt.Fprintf(&b.sb, "<p><code><b>%s</b></code></p>\n", html.EscapeString(strings.TrimSpace(header)))
+
+	lexer := lexers.Match(relativePath)
+	if lexer == nil {
+		lexer = lexers.Analyse(string(content))
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	// Format into a scratch buffer so a failure halfway through leaves no partial output.
+	var highlighted strings.Builder
+	iterator, err := lexer.Tokenise(nil, string(content))
+	if err == nil {
+		err = b.formatter.Format(&highlighted, b.style, iterator)
+	}
+	if err != nil {
+		fmt.Fprintf(&b.sb, "<pre>%s</pre>\n", html.EscapeString(string(content)))
+		return
+	}
+	b.sb.WriteString(highlighted.String())
+}
+
+// String returns the complete HTML document.
+func (b *htmlBundleBuilder) String() string {
+	return b.sb.String() + "</body></html>\n"
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLBundleBuilder(t *testing.T) {
+	b := newHTMLBundleBuilder()
+	b.addPreamble("Review <this>\nplease")
+	b.addFile("main.go", "--- FILENAME: main.go | Size: 13 bytes ---\n", []byte("package main\n"))
+	b.addFile("notes.unknown-ext", "--- FILENAME: notes.unknown-ext ---\n", []byte("a < b & c\n"))
+	doc := b.String()
+
+	for _, want := range []string{
+		"<html><head><meta charset=\"utf-8\"></head><body>\n",
+		"<p>Review &lt;this&gt;<br>\nplease</p>\n",
+		"<p><code><b>--- FILENAME: main.go | Size: 13 bytes ---</b></code></p>\n",
+		"<p><code><b>--- FILENAME: notes.unknown-ext ---</b></code></p>\n",
+		"</body></html>\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "class=") {
+		t.Error("document uses CSS classes instead of inline styles")
+	}
+	if strings.Contains(doc, "a < b") {
+		t.Error("file content is not escaped")
+	}
+	if !strings.Contains(doc, "package") || !strings.Contains(doc, "&lt;") {
+		t.Errorf("file contents missing:\n%s", doc)
+	}
+}
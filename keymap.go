@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Configurable Keybindings ---
+
+// Key scopes: the modes and overlays in which a binding is matched. Two actions of the same
+// scope must not share a key, since only the first one checked in Update would ever run.
+const (
+	scopeNormal   = "normal mode"
+	scopeFilter   = "filter mode"
+	scopeVisual   = "visual mode"
+	scopeSets     = "selection sets"
+	scopePatterns = "selection patterns"
+	scopeHistory  = "copy history"
+	scopePresets  = "team presets"
+	scopeRemap    = "moved files prompt"
+	scopeQuit     = "unsaved changes prompt"
+	scopeMerge    = "merge prompt"
+)
+
+// keyAction is a configurable binding, as named in the [keys] table of the user config.
+type keyAction struct {
+	name    string       // Name in the config file; the keyMap field name in lower camel case.
+	binding *key.Binding // Binding in the keyMap.
+	scopes  []string     // Scopes in which the binding is matched.
+}
+
+// actions lists every configurable binding of k with its scopes.
+func (k *keyMap) actions() []keyAction {
+	// Navigation is shared by the list and the overlays; bulk and preview keys are checked
+	// before the visual range keys, so they are part of visual mode too.
+	listNav := []string{scopeNormal, scopeSets, scopePatterns, scopeHistory, scopePresets}
+	everywhere := []string{scopeNormal, scopeFilter, scopeVisual}
+	return []keyAction{
+		{"cursorUp", &k.CursorUp, listNav},
+		{"cursorDown", &k.CursorDown, listNav},
+		{"prevPage", &k.PrevPage, []string{scopeNormal}},
+		{"nextPage", &k.NextPage, []string{scopeNormal}},
+		{"goToStart", &k.GoToStart, []string{scopeNormal}},
+		{"goToEnd", &k.GoToEnd, []string{scopeNormal}},
+		{"showHelp", &k.ShowHelp, []string{scopeNormal}},
+		{"toggle", &k.Toggle, []string{scopeNormal}},
+		{"confirm", &k.Confirm, []string{scopeNormal}},
+		{"quit", &k.Quit, everywhere},
+		{"toggleHidden", &k.ToggleHidden, []string{scopeNormal}},
+		{"startFilter", &k.StartFilter, []string{scopeNormal}},
+		{"clearFilter", &k.ClearFilter, []string{scopeFilter}},
+		{"filterDown", &k.FilterDown, []string{scopeFilter}},
+		{"filterUp", &k.FilterUp, []string{scopeFilter}},
+		{"filterToggle", &k.FilterToggle, []string{scopeFilter}},
+		{"clearSelected", &k.ClearSelected, []string{scopeNormal}},
+		{"filterClearSelected", &k.FilterClearSelected, []string{scopeFilter}},
+		{"selectVisible", &k.SelectVisible, everywhere},
+		{"deselectVisible", &k.DeselectVisible, everywhere},
+		{"invertVisible", &k.InvertVisible, everywhere},
+		{"selectMatches", &k.SelectMatches, everywhere},
+		{"contentSearch", &k.ContentSearch, []string{scopeNormal, scopeFilter}},
+		{"visual", &k.Visual, []string{scopeNormal}},
+		{"visualToggle", &k.VisualToggle, []string{scopeVisual}},
+		{"visualSelect", &k.VisualSelect, []string{scopeVisual}},
+		{"visualDeselect", &k.VisualDeselect, []string{scopeVisual}},
+		{"visualExit", &k.VisualExit, []string{scopeVisual}},
+		{"save", &k.Save, []string{scopeNormal}},
+		{"undo", &k.Undo, []string{scopeNormal}},
+		{"redo", &k.Redo, []string{scopeNormal}},
+		{"filterUndo", &k.FilterUndo, []string{scopeFilter}},
+		{"filterRedo", &k.FilterRedo, []string{scopeFilter}},
+		{"preview", &k.Preview, everywhere},
+		{"previewDown", &k.PreviewDown, []string{scopeNormal, scopeVisual}},
+		{"previewUp", &k.PreviewUp, []string{scopeNormal, scopeVisual}},
+		{"previewPgDown", &k.PreviewPgDown, everywhere},
+		{"previewPgUp", &k.PreviewPgUp, everywhere},
+		{"sort", &k.Sort, []string{scopeNormal}},
+		{"filterSort", &k.FilterSort, []string{scopeFilter}},
+		{"details", &k.Details, []string{scopeNormal}},
+		{"sets", &k.Sets, []string{scopeNormal}},
+		{"patterns", &k.Patterns, []string{scopeNormal}},
+		{"patternsAdd", &k.PatternsAdd, []string{scopePatterns}},
+		{"patternsDelete", &k.PatternsDelete, []string{scopePatterns}},
+		{"patternsClose", &k.PatternsClose, []string{scopePatterns}},
+		{"history", &k.History, []string{scopeNormal}},
+		{"historyDetails", &k.HistoryDetails, []string{scopeHistory}},
+		{"historyRestore", &k.HistoryRestore, []string{scopeHistory}},
+		{"historyRecopy", &k.HistoryRecopy, []string{scopeHistory}},
+		{"historyClose", &k.HistoryClose, []string{scopeHistory}},
+		{"presets", &k.Presets, []string{scopeNormal}},
+		{"presetLoad", &k.PresetLoad, []string{scopePresets}},
+		{"presetClose", &k.PresetClose, []string{scopePresets}},
+		{"pickerLoad", &k.PickerLoad, []string{scopeSets}},
+		{"pickerSaveAs", &k.PickerSaveAs, []string{scopeSets}},
+		{"pickerRename", &k.PickerRename, []string{scopeSets}},
+		{"pickerDelete", &k.PickerDelete, []string{scopeSets}},
+		{"pickerClose", &k.PickerClose, []string{scopeSets}},
+		{"remapAccept", &k.RemapAccept, []string{scopeRemap}},
+		{"remapDecline", &k.RemapDecline, []string{scopeRemap}},
+		{"quitSave", &k.QuitSave, []string{scopeQuit}},
+		{"quitDiscard", &k.QuitDiscard, []string{scopeQuit}},
+		{"quitCancel", &k.QuitCancel, []string{scopeQuit}},
+		{"mergeOverwrite", &k.MergeOverwrite, []string{scopeMerge}},
+		{"mergeCombine", &k.MergeCombine, []string{scopeMerge}},
+		{"mergeTheirs", &k.MergeTheirs, []string{scopeMerge}},
+		{"mergeCancel", &k.MergeCancel, []string{scopeMerge}},
+	}
+}
+
+// keyAliases maps friendlier spellings to the names Bubble Tea reports. Terminals send ctrl+m,
+// ctrl+i and ctrl+[ as enter, tab and esc, so those are aliases too.
+var keyAliases = map[string]string{
+	"space":  " ",
+	"return": "enter",
+	"escape": "esc",
+	"ctrl+m": "enter",
+	"ctrl+i": "tab",
+	"ctrl+[": "esc",
+}
+
+// keyDisplayNames are shown instead of key names in help texts.
+var keyDisplayNames = map[string]string{
+	" ":     "space",
+	"up":    "↑",
+	"down":  "↓",
+	"left":  "←",
+	"right": "→",
+}
+
+// namedKeys holds the names of all non-character keys Bubble Tea reports ("enter", "ctrl+a", ...).
+var namedKeys = func() map[string]bool {
+	names := make(map[string]bool)
+	for t := tea.KeyF20; t <= tea.KeyCtrlQuestionMark; t++ {
+		if name := t.String(); name != "" && t != tea.KeyRunes {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// normalizeKey turns a key from the config file into the name key.Matches compares against.
+// Keys are a single character or a named key, optionally prefixed with "alt+".
+func normalizeKey(name string) (string, error) {
+	if alias, ok := keyAliases[name]; ok {
+		return alias, nil
+	}
+	base, alt := strings.CutPrefix(name, "alt+")
+	if alias, ok := keyAliases[base]; ok && alt {
+		return "alt+" + alias, nil
+	}
+	if utf8.RuneCountInString(base) == 1 || namedKeys[base] {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown key '%s'", name)
+}
+
+// apply replaces the keys of the actions named in overrides and checks the result for conflicts.
+// The help text of an overridden binding lists its new keys; an empty list unbinds the action.
+func (k *keyMap) apply(overrides map[string][]string) error {
+	actions := k.actions()
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		keys := overrides[name]
+		i := slices.IndexFunc(actions, func(a keyAction) bool { return a.name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown key action '%s'", name)
+		}
+		if len(keys) == 0 && name == "quit" {
+			return fmt.Errorf("key action 'quit' needs at least one key")
+		}
+		normalized := make([]string, 0, len(keys))
+		for _, keyName := range keys {
+			keyName, err := normalizeKey(keyName)
+			if err != nil {
+				return fmt.Errorf("key action '%s': %w", name, err)
+			}
+			if !slices.Contains(normalized, keyName) {
+				normalized = append(normalized, keyName)
+			}
+		}
+		binding := actions[i].binding
+		binding.SetKeys(normalized...)
+		binding.SetHelp(helpKeys(normalized), binding.Help().Desc)
+	}
+	return k.validate()
+}
+
+// validate reports keys bound to two actions of the same scope, and plain characters bound to
+// actions of filter mode only, which would be typed into the query instead.
+func (k *keyMap) validate() error {
+	owners := make(map[string]string) // "scope\x00key" -> action name.
+	for _, action := range k.actions() {
+		if slices.Equal(action.scopes, []string{scopeFilter}) {
+			for _, keyName := range action.binding.Keys() {
+				if utf8.RuneCountInString(keyName) == 1 {
+					return fmt.Errorf("key '%s' of '%s' would be typed into the filter query; use an alt+ or ctrl+ key", displayKey(keyName), action.name)
+				}
+			}
+		}
+		for _, scope := range action.scopes {
+			for _, keyName := range action.binding.Keys() {
+				id := scope + "\x00" + keyName
+				if owner, taken := owners[id]; taken {
+					return fmt.Errorf("key '%s' is bound to both '%s' and '%s' in %s", displayKey(keyName), owner, action.name, scope)
+				}
+				owners[id] = action.name
+			}
+		}
+	}
+	return nil
+}
+
+// displayKey returns the name of a key as shown in help texts.
+func displayKey(name string) string {
+	if display, ok := keyDisplayNames[name]; ok {
+		return display
+	}
+	return name
+}
+
+// helpKeys returns the compact key list of a help entry ("space/x").
+func helpKeys(keys []string) string {
+	display := make([]string, len(keys))
+	for i, keyName := range keys {
+		display[i] = displayKey(keyName)
+	}
+	return strings.Join(display, "/")
+}
+
+// keyColumn lists all keys of the bindings for the first column of printHelp ("j, ↓, k, ↑").
+func keyColumn(bindings ...key.Binding) string {
+	var display []string
+	for _, binding := range bindings {
+		for _, keyName := range binding.Keys() {
+			display = append(display, displayKey(keyName))
+		}
+	}
+	return strings.Join(display, ", ")
+}
+
+// keyHints renders the one-line key hints of an overlay ("a add • d delete • esc close").
+func keyHints(bindings ...key.Binding) string {
+	var hints []string
+	for _, binding := range bindings {
+		if binding.Enabled() {
+			hints = append(hints, binding.Help().Key+" "+binding.Help().Desc)
+		}
+	}
+	return strings.Join(hints, " • ")
+}
+
+// normalHelpKeys are shown in the full help view ('?') in normal mode.
+func (k keyMap) normalHelpKeys() []key.Binding {
+	return []key.Binding{k.Toggle, k.ToggleHidden, k.StartFilter, k.Confirm, k.Quit, k.Save, k.Undo, k.Redo, k.ClearSelected, k.SelectVisible, k.DeselectVisible, k.InvertVisible, k.SelectMatches, k.ContentSearch, k.Visual, k.Sort, k.Details, k.Preview, k.Sets, k.Patterns, k.History, k.Presets}
+}
+
+// filterHelpKeys are shown in the full help view while filtering.
+func (k keyMap) filterHelpKeys() []key.Binding {
+	return []key.Binding{k.ClearFilter, k.FilterDown, k.FilterUp, k.FilterToggle, k.Quit, k.FilterClearSelected, k.FilterSort, k.FilterUndo, k.FilterRedo, k.SelectVisible, k.DeselectVisible, k.InvertVisible, k.SelectMatches, k.ContentSearch}
+}
+
+// filterConfirmKeys returns the keys of Confirm that work while filtering: those that are
+// neither query text nor bound to another filter-mode action.
+func (k keyMap) filterConfirmKeys() key.Binding {
+	taken := make(map[string]bool)
+	for _, action := range k.actions() {
+		if action.binding != &k.Confirm && slices.Contains(action.scopes, scopeFilter) {
+			for _, keyName := range action.binding.Keys() {
+				taken[keyName] = true
+			}
+		}
+	}
+	var keys []string
+	for _, keyName := range k.Confirm.Keys() {
+		if !taken[keyName] && keyName != " " && keyName != "backspace" && utf8.RuneCountInString(keyName) != 1 {
+			keys = append(keys, keyName)
+		}
+	}
+	return key.NewBinding(key.WithKeys(keys...))
+}
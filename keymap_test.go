@@ -0,0 +1,131 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultKeyMapIsValid(t *testing.T) {
+	keys := defaultKeyMap()
+	if err := keys.validate(); err != nil {
+		t.Fatalf("default keys: %v", err)
+	}
+}
+
+func TestKeyMapApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		wantErr   string   // Substring of the expected error; "" if none.
+		action    string   // Action whose keys are checked on success.
+		wantKeys  []string // Expected keys of action.
+		wantHelp  string   // Expected help key text of action.
+	}{
+		{
+			name:      "rebind",
+			overrides: map[string][]string{"toggle": {"x"}},
+			action:    "toggle",
+			wantKeys:  []string{"x"},
+			wantHelp:  "x",
+		},
+		{
+			name:      "aliases and duplicates",
+			overrides: map[string][]string{"toggle": {"space", "x", "space"}},
+			action:    "toggle",
+			wantKeys:  []string{" ", "x"},
+			wantHelp:  "space/x",
+		},
+		{
+			name:      "alt with an alias",
+			overrides: map[string][]string{"filterUndo": {"alt+escape"}},
+			action:    "filterUndo",
+			wantKeys:  []string{"alt+esc"},
+			wantHelp:  "alt+esc",
+		},
+		{
+			name:      "named key",
+			overrides: map[string][]string{"filterSort": {"ctrl+o"}},
+			action:    "filterSort",
+			wantKeys:  []string{"ctrl+o"},
+			wantHelp:  "ctrl+o",
+		},
+		{
+			name:      "empty list unbinds",
+			overrides: map[string][]string{"toggle": {}},
+			action:    "toggle",
+			wantKeys:  []string{},
+			wantHelp:  "",
+		},
+		{
+			name:      "same key in different scopes",
+			overrides: map[string][]string{"historyClose": {"q"}},
+			action:    "historyClose",
+			wantKeys:  []string{"q"},
+			wantHelp:  "q",
+		},
+		{
+			name:      "swapped keys",
+			overrides: map[string][]string{"undo": {"ctrl+r"}, "redo": {"u"}},
+			action:    "redo",
+			wantKeys:  []string{"u"},
+			wantHelp:  "u",
+		},
+		{
+			name:      "unknown action",
+			overrides: map[string][]string{"teleport": {"t"}},
+			wantErr:   "unknown key action 'teleport'",
+		},
+		{
+			name:      "unknown key",
+			overrides: map[string][]string{"toggle": {"hyper+x"}},
+			wantErr:   "unknown key 'hyper+x'",
+		},
+		{
+			name:      "quit unbound",
+			overrides: map[string][]string{"quit": {}},
+			wantErr:   "needs at least one key",
+		},
+		{
+			name:      "conflict in the same scope",
+			overrides: map[string][]string{"toggle": {"y"}},
+			wantErr:   "bound to both",
+		},
+		{
+			name:      "conflict with a key shared by every mode",
+			overrides: map[string][]string{"filterToggle": {"ctrl+c"}},
+			wantErr:   "in filter mode",
+		},
+		{
+			name:      "plain character for a filter action",
+			overrides: map[string][]string{"filterUndo": {"u"}},
+			wantErr:   "would be typed into the filter query",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := defaultKeyMap()
+			err := keys.apply(tt.overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("apply() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			i := slices.IndexFunc(keys.actions(), func(a keyAction) bool { return a.name == tt.action })
+			if i < 0 {
+				t.Fatalf("no action %q", tt.action)
+			}
+			binding := keys.actions()[i].binding
+			if !slices.Equal(binding.Keys(), tt.wantKeys) {
+				t.Errorf("keys = %q, want %q", binding.Keys(), tt.wantKeys)
+			}
+			if binding.Help().Key != tt.wantHelp {
+				t.Errorf("help key = %q, want %q", binding.Help().Key, tt.wantHelp)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Atomic Writes and Advisory Locking ---
+
+// stateLockTimeout bounds how long a read-modify-write waits for another yank instance.
+const stateLockTimeout = 5 * time.Second
+
+// errLockBusy is returned by tryLockFile when another process holds the lock.
+var errLockBusy = errors.New("lock is held by another process")
+
+// stateLock is an advisory lock on a persistence file, held during read-modify-write.
+type stateLock struct {
+	file *os.File // Open lock file; the OS lock is tied to this descriptor.
+	path string   // Path of the lock file, removed on unlock.
+}
+
+// lockStateFile takes the advisory lock guarding statePath. The lock lives in a separate
+// "<statePath>.lock" file because the state file itself is replaced on every write. The lock
+// file is removed on unlock; a waiter that locked a file which was removed meanwhile retries.
+func lockStateFile(statePath string) (*stateLock, error) {
+	lockPath := statePath + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed create state directory: %w", err)
+	}
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0640)
+		if err != nil {
+			return nil, fmt.Errorf("failed open lock file '%s': %w", lockPath, err)
+		}
+		err = tryLockFile(f)
+		if err == nil {
+			// Make sure the locked file is still the one at lockPath.
+			held, statErr := f.Stat()
+			current, pathErr := os.Stat(lockPath)
+			if statErr == nil && pathErr == nil && os.SameFile(held, current) {
+				return &stateLock{file: f, path: lockPath}, nil
+			}
+			unlockFile(f)
+		}
+		f.Close()
+		if err != nil && !errors.Is(err, errLockBusy) {
+			return nil, fmt.Errorf("failed lock '%s': %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("'%s' is locked by another yank instance (waited %s)", statePath, stateLockTimeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// unlock releases the lock and removes the lock file.
+func (l *stateLock) unlock() {
+	// Remove while still holding the lock, so waiters notice the stale descriptor and retry.
+	_ = os.Remove(l.path)
+	unlockFile(l.file)
+	l.file.Close()
+}
+
+// writeFileAtomic writes content to path via a temporary file in the same directory and a
+// rename, so readers (and crashes) never observe a partially written file.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// Clean up the temporary file on any failure below; after the rename this is a no-op.
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package main
+
+import "os"
+
+// tryLockFile is a no-op on platforms without a supported locking primitive; writes are
+// still atomic, but concurrent instances are not serialised.
+func tryLockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op (see tryLockFile).
+func unlockFile(f *os.File) {}
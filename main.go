@@ -4,6 +4,7 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,18 +12,16 @@ import (
 	"io/fs"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
-	"sort"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/lithammer/fuzzysearch/fuzzy"
 )
 
 const (
@@ -33,14 +32,17 @@ const (
 
 var (
 	// Define styles using lipgloss for UI elements. Reusable styles improve consistency.
+	// The coloured styles are set by applyTheme at startup.
 	docStyle          = lipgloss.NewStyle().Margin(1, 2)
-	titleStyle        = lipgloss.NewStyle().MarginLeft(0).Bold(true).Foreground(lipgloss.Color("62"))
+	titleStyle        lipgloss.Style
 	itemStyle         = lipgloss.NewStyle().PaddingLeft(0)
-	selectedStyle     = lipgloss.NewStyle().PaddingLeft(0).Foreground(lipgloss.Color("75")).Bold(true)
-	checkedStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
-	helpStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
-	filterPromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	selectedStyle     lipgloss.Style
+	checkedStyle      lipgloss.Style
+	helpStyle         lipgloss.Style
+	errorStyle        lipgloss.Style
+	filterPromptStyle lipgloss.Style
+	patternStyle      lipgloss.Style
+	matchStyle        lipgloss.Style // Characters of a path matched by the filter query.
 )
 
 // --- Bubble Tea Model ---
@@ -48,7 +50,8 @@ var (
 // item represents a single file entry in the list component.
 // It implements the list.Item interface necessary for bubbles/list.
 type item struct {
-	name string // Stores the relative path of the file from the target directory
+	name string      // Stores the relative path of the file from the target directory
+	hit  *contentHit // Match of a content search result (nil otherwise).
 }
 
 // Title is required by the list.Item interface. Returns the text to display for the item.
@@ -75,6 +78,39 @@ type model struct {
 	statusTimer       *time.Timer     // Timer used to clear the status message after a delay.
 	isFiltering       bool            // Flag indicating if search/filter mode is active.
 	filterQuery       string          // Stores the current user-entered search query.
+	filterTerms       filterQuery     // Parsed filterQuery while filtering paths; the rows highlight its matches.
+	lastFilterQuery   string          // Last non-empty filter query, persisted with the session.
+	prefillFilter     bool            // Flag to start the next filter with lastFilterQuery (set when restored from the session).
+	lastErr           error           // Non-fatal error (e.g., clipboard failure) shown in the info line until the next action.
+	copyHTML          bool            // Flag to also place a syntax-highlighted text/html rendering on the clipboard.
+	htmlUnavailable   bool            // Flag set when the clipboard backend cannot hold HTML; copyHTML is kept for the session file.
+	verifyCopy        bool            // Flag to read the clipboard back after copying and compare hashes.
+	state             selectionState  // Last known content of the persistence file (named selection sets).
+	activeSet         string          // Name of the loaded selection set ("" if none); updated on copy.
+	picker            setPicker       // Overlay for loading, saving, renaming, and deleting named sets.
+	remap             remapPrompt     // Overlay offering to re-map saved paths of moved files.
+	baseSelection     []string        // Current selection as last read from or written to disk; base of the merge prompt.
+	basePatterns      []string        // Patterns as last read from or written to disk; base of the merge prompt.
+	merge             mergePrompt     // Overlay resolving a selection changed on disk by another instance.
+	patterns          []string        // Glob patterns selecting files dynamically ("!" prefix excludes).
+	patternMatched    map[string]bool // Files matched by patterns; shared with the delegate, never reassigned.
+	patternEditor     patternEditor   // Overlay for adding and removing patterns.
+	history           historyPanel    // Overlay listing past copies of the directory.
+	historyMode       string          // What is recorded in the copy history (historyModeFull, ...).
+	presets           presetCatalog   // Team presets from the committed presets file.
+	activePreset      string          // Name of the loaded team preset ("" if none); its preamble is copied.
+	presetPicker      presetPicker    // Overlay listing the team presets.
+	saveOnQuit        bool            // Flag to save unsaved selection changes on quit instead of asking.
+	quitPrompt        quitPrompt      // Overlay asking what to do with unsaved changes on quit.
+	undoHistory       undoHistory     // Recorded selection changes for undo and redo.
+	width, height     int             // Terminal size from the last tea.WindowSizeMsg.
+	preview           previewPane     // Syntax-highlighted preview of the focused file.
+	sortMode          string          // Order of the file list (sortModePath, ...); persisted with the session.
+	walkOrder         map[string]int  // Scan position of every file; the final sort tie breaker.
+	fileStats         *fileStatCache  // Cached file metadata for sorting and detail rows; shared with the delegate.
+	showDetails       bool            // Flag to show size, age, line count and token estimate in every row.
+	visual            visualMode      // Vim-style range selection state.
+	search            contentSearch   // Content search state; filtering searches contents while search.active.
 }
 
 // --- Keybindings ---
@@ -82,19 +118,109 @@ type model struct {
 // keyMap defines the keybindings used by the application, utilizing bubbles/key
 // for easy definition and display in help messages.
 type keyMap struct {
-	Toggle        key.Binding // Toggles selection for the focused item (space, m).
-	Confirm       key.Binding // Confirms selection, copies data, saves state, and quits (y, enter).
-	Quit          key.Binding // Quits the application without copying (q, ctrl+c).
-	ToggleHidden  key.Binding // Toggles visibility of hidden paths (.).
-	StartFilter   key.Binding // Key to activate filter mode (/).
-	ClearFilter   key.Binding // Key to clear filter query and exit filter mode (esc).
-	ClearSelected key.Binding // Key to clear selected files.
-	// NOTE: Ctrl+J, Ctrl+K, Ctrl+M for filter-mode actions are handled directly via msg.Type in Update.
+	CursorUp            key.Binding // Moves the cursor up in the list and the overlays (k, ↑).
+	CursorDown          key.Binding // Moves the cursor down in the list and the overlays (j, ↓).
+	PrevPage            key.Binding // Shows the previous page of the list (h, ←, pgup, b).
+	NextPage            key.Binding // Shows the next page of the list (l, →, pgdown, f, d).
+	GoToStart           key.Binding // Moves the cursor to the first item (g, home).
+	GoToEnd             key.Binding // Moves the cursor to the last item (G, end).
+	ShowHelp            key.Binding // Shows or hides the full help view (?).
+	Toggle              key.Binding // Toggles selection for the focused item (space, m).
+	Confirm             key.Binding // Confirms selection, copies data, saves state, and quits (y, enter).
+	Quit                key.Binding // Quits the application without copying (q, ctrl+c).
+	ToggleHidden        key.Binding // Toggles visibility of hidden paths (.).
+	StartFilter         key.Binding // Key to activate filter mode (/).
+	ClearFilter         key.Binding // Key to clear filter query and exit filter mode (esc).
+	FilterDown          key.Binding // Moves the cursor down in the filter results (ctrl+j).
+	FilterUp            key.Binding // Moves the cursor up in the filter results (ctrl+k).
+	FilterToggle        key.Binding // Toggles selection for the focused filter result (ctrl+m, sent as enter).
+	ClearSelected       key.Binding // Key to clear the explicitly selected files; patterns are kept.
+	FilterClearSelected key.Binding // Clears the selection of the filter results only (alt+c).
+	SelectVisible       key.Binding // Selects all visible files (ctrl+a).
+	DeselectVisible     key.Binding // Deselects all visible files (ctrl+n).
+	InvertVisible       key.Binding // Inverts the selection of all visible files (ctrl+t).
+	SelectMatches       key.Binding // Selects all matches of the (last) filter query (ctrl+f).
+	ContentSearch       key.Binding // Searches file contents; switches between path and content search while filtering (ctrl+g).
+	Visual              key.Binding // Starts a visual range selection at the cursor (v, V).
+	VisualToggle        key.Binding // Toggles the visual range as a whole (space, m).
+	VisualSelect        key.Binding // Selects the visual range (a).
+	VisualDeselect      key.Binding // Deselects the visual range (d, x).
+	VisualExit          key.Binding // Leaves visual mode (esc, v, V).
+	Save                key.Binding // Saves the selection without copying (w).
+	Undo                key.Binding // Undoes the last selection change (u).
+	Redo                key.Binding // Redoes the last undone selection change (ctrl+r).
+	FilterUndo          key.Binding // Undoes the last selection change while filtering (alt+u).
+	FilterRedo          key.Binding // Redoes the last undone selection change while filtering (ctrl+r).
+	Preview             key.Binding // Shows or hides the file preview (tab).
+	PreviewDown         key.Binding // Scrolls the preview down by a line (J).
+	PreviewUp           key.Binding // Scrolls the preview up by a line (K).
+	PreviewPgDown       key.Binding // Scrolls the preview down by half a page (ctrl+d).
+	PreviewPgUp         key.Binding // Scrolls the preview up by half a page (ctrl+u).
+	Sort                key.Binding // Cycles through the sort modes (o).
+	FilterSort          key.Binding // Cycles through the sort modes while filtering (alt+o).
+	Details             key.Binding // Shows or hides the detail columns of the rows (i).
+	Sets                key.Binding // Opens the named selection set picker (s).
+	Patterns            key.Binding // Opens the selection pattern editor (p).
+	PatternsAdd         key.Binding // Adds a pattern in the pattern editor (a).
+	PatternsDelete      key.Binding // Deletes the focused pattern in the pattern editor (d).
+	PatternsClose       key.Binding // Closes the pattern editor (esc, p).
+	History             key.Binding // Opens the copy history panel (H).
+	HistoryDetails      key.Binding // Shows the file list of the focused history entry (enter).
+	HistoryRestore      key.Binding // Restores the selection of the focused history entry (r).
+	HistoryRecopy       key.Binding // Copies the stored bytes of the focused history entry again (y).
+	HistoryClose        key.Binding // Closes the history panel (esc, H).
+	Presets             key.Binding // Opens the team preset picker (P).
+	PresetLoad          key.Binding // Loads the focused preset in the preset picker (enter).
+	PresetClose         key.Binding // Closes the preset picker (esc, P).
+	PickerLoad          key.Binding // Loads the focused set in the picker (enter).
+	PickerSaveAs        key.Binding // Saves the current selection under a new name in the picker (n).
+	PickerRename        key.Binding // Renames the focused set in the picker (r).
+	PickerDelete        key.Binding // Deletes the focused set in the picker (d).
+	PickerClose         key.Binding // Closes the picker (esc, s).
+	RemapAccept         key.Binding // Re-maps saved paths of moved files in the remap prompt (y, enter).
+	RemapDecline        key.Binding // Drops saved paths of moved files in the remap prompt (n, esc).
+	QuitSave            key.Binding // Saves unsaved changes and quits in the quit prompt (w, y).
+	QuitDiscard         key.Binding // Discards unsaved changes and quits in the quit prompt (d, n).
+	QuitCancel          key.Binding // Returns to the list from the quit prompt (esc).
+	MergeOverwrite      key.Binding // Overwrites the selection changed on disk with the own one and copies (o).
+	MergeCombine        key.Binding // Applies the changes made on disk to the own selection (m).
+	MergeTheirs         key.Binding // Replaces the own selection with the one on disk (t).
+	MergeCancel         key.Binding // Closes the merge prompt without changes (esc).
 }
 
-// defaultKeyMap returns the standard key configuration for the application.
+// defaultKeyMap returns the standard key configuration for the application. The user config
+// can override every binding (see keyMap.apply).
 func defaultKeyMap() keyMap {
 	return keyMap{
+		// The list navigation keys mirror the defaults of bubbles/list, except that 'u' is undo.
+		CursorUp: key.NewBinding(
+			key.WithKeys("k", "up"),
+			key.WithHelp("↑/k", "up"),
+		),
+		CursorDown: key.NewBinding(
+			key.WithKeys("j", "down"),
+			key.WithHelp("↓/j", "down"),
+		),
+		PrevPage: key.NewBinding(
+			key.WithKeys("h", "left", "pgup", "b"),
+			key.WithHelp("←/h/pgup", "prev page"),
+		),
+		NextPage: key.NewBinding(
+			key.WithKeys("l", "right", "pgdown", "f", "d"),
+			key.WithHelp("→/l/pgdn", "next page"),
+		),
+		GoToStart: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g/home", "go to start"),
+		),
+		GoToEnd: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G/end", "go to end"),
+		),
+		ShowHelp: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "more"),
+		),
 		Toggle: key.NewBinding(
 			key.WithKeys(" ", "m"),
 			key.WithHelp("space/m", "toggle select"),
@@ -119,10 +245,226 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "clear filter"),
 		),
+		FilterDown: key.NewBinding(
+			key.WithKeys("ctrl+j"),
+			key.WithHelp("ctrl+j", "down"),
+		),
+		FilterUp: key.NewBinding(
+			key.WithKeys("ctrl+k"),
+			key.WithHelp("ctrl+k", "up"),
+		),
+		FilterToggle: key.NewBinding(
+			key.WithKeys("enter"), // Terminals send ctrl+m as enter; the two cannot be told apart.
+			key.WithHelp("enter", "toggle select"),
+		),
 		ClearSelected: key.NewBinding(
 			key.WithKeys("c", "C"),
 			key.WithHelp("c/C", "clear selected"),
 		),
+		FilterClearSelected: key.NewBinding(
+			key.WithKeys("alt+c"), // Plain characters are part of the query.
+			key.WithHelp("alt+c", "clear results"),
+		),
+		SelectVisible: key.NewBinding(
+			key.WithKeys("ctrl+a"),
+			key.WithHelp("ctrl+a", "select visible"),
+		),
+		DeselectVisible: key.NewBinding(
+			key.WithKeys("ctrl+n"),
+			key.WithHelp("ctrl+n", "deselect visible"),
+		),
+		InvertVisible: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "invert visible"),
+		),
+		SelectMatches: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "select filter matches"),
+		),
+		ContentSearch: key.NewBinding(
+			key.WithKeys("ctrl+g"),
+			key.WithHelp("ctrl+g", "search contents"),
+		),
+		Visual: key.NewBinding(
+			key.WithKeys("v", "V"),
+			key.WithHelp("v", "visual range"),
+		),
+		VisualToggle: key.NewBinding(
+			key.WithKeys(" ", "m"),
+			key.WithHelp("space", "toggle"),
+		),
+		VisualSelect: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "select"),
+		),
+		VisualDeselect: key.NewBinding(
+			key.WithKeys("d", "x"),
+			key.WithHelp("d", "deselect"),
+		),
+		VisualExit: key.NewBinding(
+			key.WithKeys("esc", "v", "V"),
+			key.WithHelp("esc", "cancel"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "save selection"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		FilterUndo: key.NewBinding(
+			key.WithKeys("alt+u"), // Plain characters are part of the query.
+			key.WithHelp("alt+u", "undo"),
+		),
+		FilterRedo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		Details: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "toggle details"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "cycle sort mode"),
+		),
+		FilterSort: key.NewBinding(
+			key.WithKeys("alt+o"), // Plain characters are part of the query.
+			key.WithHelp("alt+o", "sort"),
+		),
+		Preview: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "toggle preview"),
+		),
+		PreviewDown: key.NewBinding(
+			key.WithKeys("J"),
+			key.WithHelp("J", "scroll preview down"),
+		),
+		PreviewUp: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", "scroll preview up"),
+		),
+		PreviewPgDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "preview page down"),
+		),
+		PreviewPgUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "preview page up"),
+		),
+		Sets: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "selection sets"),
+		),
+		Patterns: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "patterns"),
+		),
+		PatternsAdd: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "add"),
+		),
+		PatternsDelete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		PatternsClose: key.NewBinding(
+			key.WithKeys("esc", "p"),
+			key.WithHelp("esc", "close"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "copy history"),
+		),
+		HistoryDetails: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "files"),
+		),
+		HistoryRestore: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "restore selection"),
+		),
+		HistoryRecopy: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy again"),
+		),
+		HistoryClose: key.NewBinding(
+			key.WithKeys("esc", "H"),
+			key.WithHelp("esc", "close"),
+		),
+		Presets: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "team presets"),
+		),
+		PresetLoad: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "load"),
+		),
+		PresetClose: key.NewBinding(
+			key.WithKeys("esc", "P"),
+			key.WithHelp("esc", "close"),
+		),
+		PickerLoad: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "load"),
+		),
+		PickerSaveAs: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "save selection as"),
+		),
+		PickerRename: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "rename"),
+		),
+		PickerDelete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		PickerClose: key.NewBinding(
+			key.WithKeys("esc", "s"),
+			key.WithHelp("esc", "close"),
+		),
+		RemapAccept: key.NewBinding(
+			key.WithKeys("y", "enter"),
+			key.WithHelp("y", "re-map to the new paths"),
+		),
+		RemapDecline: key.NewBinding(
+			key.WithKeys("n", "esc"),
+			key.WithHelp("n", "drop the missing entries"),
+		),
+		QuitSave: key.NewBinding(
+			key.WithKeys("w", "y"),
+			key.WithHelp("w", "save and quit"),
+		),
+		QuitDiscard: key.NewBinding(
+			key.WithKeys("d", "n"),
+			key.WithHelp("d", "discard and quit"),
+		),
+		QuitCancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		MergeOverwrite: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "overwrite with mine"),
+		),
+		MergeCombine: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "merge both"),
+		),
+		MergeTheirs: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "take theirs"),
+		),
+		MergeCancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
 	}
 }
 
@@ -143,23 +485,39 @@ func clearStatusCmd(d time.Duration) tea.Cmd {
 	}
 }
 
+// setStatus shows a temporary status message and returns the command that clears it.
+func (m *model) setStatus(text string) tea.Cmd {
+	m.statusMessage = text
+	if m.statusTimer != nil {
+		m.statusTimer.Stop()
+	}
+	return clearStatusCmd(2 * time.Second)
+}
+
 // --- Model Methods ---
 
 // initialModel sets up the initial state of the application model.
-// It takes the absolute path of the target directory as input.
-func initialModel(targetDir string) model {
+// It takes the absolute path of the target directory and the effective keybindings as input.
+func initialModel(targetDir string, keys keyMap) model {
 	m := model{
 		targetDir:   targetDir,
 		selected:    make(map[string]bool),
-		keys:        defaultKeyMap(),
+		keys:        keys,
 		showHidden:  false,
 		isFiltering: false,
 		filterQuery: "",
+		picker:      newSetPicker(),
+
+		patternMatched: make(map[string]bool),
+		patternEditor:  newPatternEditor(),
+		preview:        newPreviewPane(targetDir),
+		sortMode:       sortModePath,
+		fileStats:      newFileStatCache(targetDir),
 	}
 
 	// --- Load Files and Selection State ---
 	// Perform the recursive file scan and load previous selections from the .yank file.
-	allFiles, previouslySelectedFiles, err := loadFilesAndSelectionRecursive(targetDir)
+	allFiles, state, renames, err := loadFilesAndSelectionRecursive(targetDir)
 	if err != nil {
 		// If loading fails (e.g., cannot read target directory), store the error.
 		// The View method will detect this error and display it instead of the list.
@@ -169,33 +527,63 @@ func initialModel(targetDir string) model {
 	} else {
 		m.allAvailableFiles = allFiles
 	}
+	m.indexWalkOrder()
 
 	// Populate the selection map based on data loaded from the .yank file.
-	for _, selRelativePath := range previouslySelectedFiles {
+	m.state = state
+	// Presets are optional; a broken presets file is reported without blocking the selection.
+	if presets, err := loadPresets(targetDir); err != nil {
+		m.lastErr = err
+	} else {
+		m.presets = presets
+	}
+	for _, selRelativePath := range state.current {
 		m.selected[selRelativePath] = true
 	}
 
 	// --- Setup the bubbles/list Component ---
-	delegate := newItemDelegate(&m.selected)     // Create our custom delegate for rendering items
-	l := list.New([]list.Item{}, delegate, 0, 0) // Initialize list with empty items (populated by refreshListItems)
+	delegate := newItemDelegate(&m.selected, &m.patternMatched, m.fileStats) // Create our custom delegate for rendering items
+	l := list.New([]list.Item{}, delegate, 0, 0)                             // Initialize list with empty items (populated by refreshListItems)
 	l.Styles.Title = titleStyle
 	// Define which keybindings are shown in the full help view ('?'), dynamically
 	// changing based on whether the user is currently filtering.
 	l.AdditionalFullHelpKeys = func() []key.Binding {
 		if m.isFiltering { // When filtering, only show relevant keys.
-			// Note: Ctrl+J/K/M aren't easily shown here as they aren't standard Bindings.
-			return []key.Binding{m.keys.ClearFilter, m.keys.Confirm, m.keys.Quit}
+			return m.keys.filterHelpKeys()
 		}
 		// When not filtering, show the main action keys.
-		return []key.Binding{m.keys.Toggle, m.keys.ToggleHidden, m.keys.StartFilter, m.keys.Confirm, m.keys.Quit, m.keys.ClearSelected}
+		return m.keys.normalHelpKeys()
 	}
 	// Configure list appearance and behavior.
 	l.SetShowStatusBar(false)    // We handle status messages separately below the list.
 	l.SetFilteringEnabled(false) // Disable the list's built-in filtering; we implement our own fuzzy search.
 	l.SetShowHelp(true)          // Enable the default help view feature (toggled by '?').
+	// Navigation uses the configurable bindings. Quitting always goes through m.keys.Quit, which
+	// asks about unsaved changes, so the list's own quit keys are disabled.
+	l.KeyMap.CursorUp = m.keys.CursorUp
+	l.KeyMap.CursorDown = m.keys.CursorDown
+	l.KeyMap.PrevPage = m.keys.PrevPage
+	l.KeyMap.NextPage = m.keys.NextPage
+	l.KeyMap.GoToStart = m.keys.GoToStart
+	l.KeyMap.GoToEnd = m.keys.GoToEnd
+	l.KeyMap.ShowFullHelp = m.keys.ShowHelp
+	l.KeyMap.CloseFullHelp = m.keys.ShowHelp
+	l.KeyMap.CloseFullHelp.SetHelp(m.keys.ShowHelp.Help().Key, "close help")
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{m.keys.Quit} }
 
 	m.list = l
+	m.patterns = state.patterns
+	matchPatterns(m.patterns, m.allAvailableFiles, m.patternMatched)
 	m.refreshListItems() // Perform the initial population of list items based on loaded state.
+	m.restoreSession(state.session)
+
+	m.setBase(sortedCopy(state.current), state.patterns)
+
+	// Offer to re-map saved paths whose files were moved since the last save.
+	if len(renames) > 0 {
+		m.remap = remapPrompt{active: true, candidates: renames}
+	}
 
 	return m
 }
@@ -206,8 +594,8 @@ func initialModel(targetDir string) model {
 func (m *model) refreshListItems() {
 	var visibleItems []list.Item
 
-	// Iterate through all files found during the initial scan.
-	for _, relativePath := range m.allAvailableFiles {
+	// Iterate through all files found during the initial scan, in the order of the sort mode.
+	for _, relativePath := range m.sortedFiles() {
 
 		// Check if the path contains a hidden component (directory or file starting with '.')
 		pathContainsHidden := false
@@ -220,14 +608,14 @@ func (m *model) refreshListItems() {
 			}
 		}
 
-		isSelected := m.selected[relativePath]
+		isSelected := m.selected[relativePath] || m.patternMatched[relativePath]
 
 		// --- Visibility Logic ---
 		// Determine if this item should be visible in the list based on current state:
 		// Show if:
 		// 1. Its path does NOT contain any hidden component, OR
 		// 2. The global 'showHidden' flag is currently true, OR
-		// 3. The item itself is selected (selected items bypass the hidden toggle).
+		// 3. The item itself is selected, explicitly or by a pattern (selected items bypass the hidden toggle).
 		if !pathContainsHidden || m.showHidden || isSelected {
 			visibleItems = append(visibleItems, item{name: relativePath})
 		}
@@ -245,7 +633,7 @@ func (m *model) refreshListItems() {
 		}
 	}
 
-	m.list.SetItems(visibleItems)
+	m.setListItems(visibleItems)
 
 	// If we recorded a focused item, try to find it in the *new* list and restore focus.
 	if currentRelativePath != "" {
@@ -257,44 +645,101 @@ func (m *model) refreshListItems() {
 		}
 	}
 
-	// Set a simple, static title for the list (removed dynamic hidden counts).
-	m.list.Title = "Select files:"
+	// Set a simple title for the list, naming the loaded selection set if any.
+	m.list.Title = "Select files"
+	if m.activeSet != "" {
+		m.list.Title = fmt.Sprintf("Select files [set: %s]", m.activeSet)
+	} else if m.activePreset != "" {
+		m.list.Title = fmt.Sprintf("Select files [preset: %s]", m.activePreset)
+	}
+	m.list.Title += m.sortTitleSuffix() + ":"
+}
+
+// setListItems replaces the rows of the list. In visual mode the delegate is refreshed as well,
+// since the row of the range anchor may have moved.
+func (m *model) setListItems(items []list.Item) {
+	m.list.SetItems(items)
+	if m.visual.active {
+		m.updateDelegate()
+	}
 }
 
-// applyFilter performs fuzzy search on allAvailableFiles using the model's filterQuery
+// applyFilter matches allAvailableFiles against the model's filterQuery (see parseFilterQuery)
 // and updates the list component's items with the ranked results.
 // If the query is empty, it reverts to the normal (potentially hidden-filtered) view
 // by calling refreshListItems.
 func (m *model) applyFilter() {
+	// Content search results are only re-ordered; a new query starts a new search instead.
+	if m.search.active {
+		m.showContentResults()
+		return
+	}
 	// If the filter query is empty, restore the default filtered view.
 	if m.filterQuery == "" {
-		m.list.Title = fmt.Sprintf("Filter results for '%s':", m.filterQuery)
+		m.filterTerms = nil
+		m.updateDelegate()
+		m.list.Title = fmt.Sprintf("Filter results for '%s'%s:", m.filterQuery, m.sortTitleSuffix())
 		m.refreshListItems() // Shows items respecting showHidden and selected state.
 		return
 	}
 
-	// Perform case-insensitive fuzzy search using the imported library.
-	// RankFindFold finds matches and provides ranks (based on edit distance, etc.).
-	ranks := fuzzy.RankFindFold(m.filterQuery, m.allAvailableFiles)
+	// Match every term of the query (see parseFilterQuery). An invalid query, such as a regular
+	// expression still being typed, shows no results and the error in the title.
+	query, err := parseFilterQuery(m.filterQuery)
+	m.filterTerms = query
+	m.updateDelegate()
+	if err != nil {
+		m.setListItems(nil)
+		m.list.Title = errorStyle.Render(fmt.Sprintf("Filter '%s': %v", m.filterQuery, err))
+		return
+	}
+	matches := query.matchFiles(m.allAvailableFiles)
 
-	// Sort the ranks: Best matches (lowest distance, highest score) come first.
-	sort.Sort(ranks)
+	// Sort the matches: Best matches (highest fuzzy score, see fuzzyMatch) come first; equally
+	// good matches are ordered by the sort mode.
+	slices.SortStableFunc(matches, func(a, b filterMatch) int {
+		if a.score != b.score {
+			return cmp.Compare(b.score, a.score)
+		}
+		return m.compareFiles(a.path, b.path)
+	})
 
 	var filteredItems []list.Item
-	for _, r := range ranks {
-		// r.Target holds the original string (relative path) that matched.
-		filteredItems = append(filteredItems, item{name: r.Target})
+	for _, match := range matches {
+		filteredItems = append(filteredItems, item{name: match.path})
 	}
 
-	m.list.SetItems(filteredItems)
-	m.list.Title = fmt.Sprintf("Filter results for '%s':", m.filterQuery)
+	m.setListItems(filteredItems)
+	// Focus the best match; the previous cursor position may lie beyond the new results.
+	m.list.Select(0)
+	m.list.Title = fmt.Sprintf("Filter results for '%s'%s:", m.filterQuery, m.sortTitleSuffix())
 }
 
 // Init is the first command executed when the application starts.
 // It can be used to trigger initial asynchronous operations.
 func (m model) Init() tea.Cmd {
-	// No initial async operations needed in this application.
-	return nil
+	// Check the clipboard up front so a missing tool or dead display shows up
+	// before the user has spent time curating a selection.
+	return tea.Batch(checkClipboardCmd, waitForLineCount(m.fileStats.results), waitForPreview(m.preview.cache.results))
+}
+
+// --- Clipboard Messages ---
+
+// clipboardCheckMsg carries the result of the startup clipboard check.
+type clipboardCheckMsg struct{ err error }
+
+// copyFailedMsg is sent by performCopyAndSave when the clipboard copy failed,
+// so the error can be shown in the TUI instead of quitting.
+type copyFailedMsg struct{ err error }
+
+// saveConflictMsg is sent by performCopyAndSave when the copy succeeded but the save found the
+// selection changed by another instance since it was loaded.
+type saveConflictMsg struct{ copiedFiles int }
+
+// checkClipboardCmd runs checkClipboard asynchronously and reports the result.
+func checkClipboardCmd() tea.Msg {
+	_, err := checkClipboard()
+	return clipboardCheckMsg{err: err}
 }
 
 // Update is the core message handling function of the Bubble Tea application.
@@ -320,9 +765,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	// Handle terminal resize events.
 	case tea.WindowSizeMsg:
-		// Recalculate list dimensions based on new window size and document margins.
-		h, v := docStyle.GetFrameSize()
-		m.list.SetSize(msg.Width-h, msg.Height-v)
+		// Recalculate list (and preview) dimensions based on new window size and document margins.
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
 
 		// Handle the custom message to clear the status bar.
 	case clearStatusMsg:
@@ -332,16 +777,84 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.statusTimer = nil
 		}
 
+		// Handle the result of the startup clipboard check.
+	case clipboardCheckMsg:
+		if msg.err != nil {
+			m.lastErr = fmt.Errorf("clipboard unavailable: %w", msg.err)
+		}
+
+		// Handle a failed clipboard copy: stay in the TUI so the user can retry or quit.
+	case copyFailedMsg:
+		m.copyStarted = false
+		m.lastErr = msg.err
+
+		// Resolve a selection saved by another instance while copying, then save and quit.
+	case saveConflictMsg:
+		m.copyStarted = false
+		if m.openMergePromptForSave(true, msg.copiedFiles) {
+			return m, nil
+		}
+		return m, m.saveAndQuit(msg.copiedFiles)
+
+	case contentSearchMsg:
+		return m, m.handleContentSearch(msg)
+
+		// Show a file loaded for the preview and wait for the next one.
+	case previewLoadedMsg:
+		m.preview.cache.store(msg.content)
+		return m, waitForPreview(m.preview.cache.results)
+
+		// Store a line count of the detail columns and wait for the next one.
+	case lineCountMsg:
+		m.fileStats.setLines(msg)
+		return m, waitForLineCount(m.fileStats.results)
+
+	case historyCopiedMsg:
+		if msg.err != nil {
+			m.lastErr = msg.err
+			return m, nil
+		}
+		return m, m.setStatus(fmt.Sprintf("Copied history entry #%d again (%s)", msg.entry.ID, formatByteSize(msg.entry.Bytes)))
+
 		// Handle keyboard input events.
 	case tea.KeyMsg:
+		// An error is shown until the next action; otherwise it would hide every later status
+		// (e.g., a clipboard warning from the start, the visual mode indicator).
+		m.lastErr = nil
+
+		// --- Selection Set Picker ---
+		// The picker captures all keys while open (it has its own text input).
+		if m.picker.active && !m.copyStarted {
+			return m.updateSetPicker(msg)
+		}
+		// The remap prompt shown after start waits for y/n.
+		if m.remap.active {
+			return m.updateRemapPrompt(msg)
+		}
+		if m.merge.active && !m.copyStarted {
+			return m.updateMergePrompt(msg)
+		}
+		if m.patternEditor.active && !m.copyStarted {
+			return m.updatePatternEditor(msg)
+		}
+		if m.history.active && !m.copyStarted {
+			return m.updateHistoryPanel(msg)
+		}
+		if m.presetPicker.active && !m.copyStarted {
+			return m.updatePresetPicker(msg)
+		}
+		if m.quitPrompt.active && !m.copyStarted {
+			return m.updateQuitPrompt(msg)
+		}
+
 		// --- Global Keybindings (handle before specific modes) ---
-		// Always allow quitting the application.
+		// Always allow quitting the application; unsaved selection changes are saved or confirmed
+		// first (a running copy saves them itself).
 		if key.Matches(msg, m.keys.Quit) {
-			m.quitting = true
-			if m.statusTimer != nil {
-				m.statusTimer.Stop() // Clean up status timer if active.
+			if m.copyStarted || m.quitting {
+				return m, m.quit()
 			}
-			return m, tea.Quit
+			return m, m.requestQuit()
 		}
 
 		// Ignore regular key presses if already quitting or if the copy process has started.
@@ -349,111 +862,191 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// --- Preview Keys ---
+		// The preview scrolls independently of the list, in normal and filter mode alike
+		// (J/K only in normal mode, where they are not part of a query).
+		switch {
+		case key.Matches(msg, m.keys.Preview):
+			m.preview.visible = !m.preview.visible
+			m.resize()
+			return m, nil
+		case m.preview.visible && key.Matches(msg, m.keys.PreviewPgDown):
+			m.scrollPreview(m.previewBodyHeight() / 2)
+			return m, nil
+		case m.preview.visible && key.Matches(msg, m.keys.PreviewPgUp):
+			m.scrollPreview(-m.previewBodyHeight() / 2)
+			return m, nil
+		case m.preview.visible && !m.isFiltering && key.Matches(msg, m.keys.PreviewDown):
+			m.scrollPreview(1)
+			return m, nil
+		case m.preview.visible && !m.isFiltering && key.Matches(msg, m.keys.PreviewUp):
+			m.scrollPreview(-1)
+			return m, nil
+		}
+
+		// --- Bulk Selection Keys ---
+		// These work on the visible items (the filter results while filtering) in both modes.
+		switch {
+		case key.Matches(msg, m.keys.SelectVisible):
+			return m, m.selectVisible()
+		case key.Matches(msg, m.keys.DeselectVisible):
+			return m, m.deselectVisible()
+		case key.Matches(msg, m.keys.InvertVisible):
+			return m, m.invertVisible()
+		case key.Matches(msg, m.keys.SelectMatches):
+			return m, m.selectFilterMatches()
+		// While filtering, clearing only affects the results.
+		case m.isFiltering && key.Matches(msg, m.keys.FilterClearSelected):
+			return m, m.deselectVisible()
+		}
+
 		// --- Filtering Mode Logic ---
 		// Handle keys differently based on whether filtering is currently active.
 		if m.isFiltering {
-			// Handle specific keys within filter mode first, primarily using msg.Type
-			// for control keys or keys handled specially during filtering.
-			switch msg.Type {
+			// Handle the filter-mode bindings first; other keys edit the query.
+			switch {
 			// Exit filter mode with Esc key.
-			case tea.KeyEsc:
+			case key.Matches(msg, m.keys.ClearFilter):
+				// Content search queries are not remembered as filter queries.
+				if m.filterQuery != "" && !m.search.active {
+					m.lastFilterQuery = m.filterQuery
+				}
+				m.stopContentSearch()
+				m.search.active = false
 				m.isFiltering = false
+				m.filterTerms = nil
+				m.updateDelegate()
 				m.filterQuery = ""
 				m.refreshListItems() // Restore normal list view (respecting showHidden).
 				// Restore normal help key display in the full help view.
-				m.list.AdditionalFullHelpKeys = func() []key.Binding {
-					return []key.Binding{m.keys.Toggle, m.keys.ToggleHidden, m.keys.StartFilter, m.keys.Confirm, m.keys.Quit, m.keys.ClearSelected}
-				}
+				m.list.AdditionalFullHelpKeys = m.keys.normalHelpKeys
 				return m, nil
 
-				// Handle backspace to delete from the filter query.
-			case tea.KeyBackspace:
-				if len(m.filterQuery) > 0 {
-					// Use rune-aware slicing to correctly handle multi-byte characters.
-					m.filterQuery = string([]rune(m.filterQuery)[:len([]rune(m.filterQuery))-1])
-					m.applyFilter()
-				}
-				return m, nil
+				// Undo and redo selection changes made while filtering or before ('alt+u', 'ctrl+r').
+			case key.Matches(msg, m.keys.FilterUndo):
+				return m, m.undo()
+			case key.Matches(msg, m.keys.FilterRedo):
+				return m, m.redo()
+
+				// Change the secondary order of the results (or the order of content matches).
+			case key.Matches(msg, m.keys.FilterSort):
+				return m, m.setStatus(m.cycleSortMode())
+
+				// Switch between matching paths and searching contents, keeping the query.
+			case key.Matches(msg, m.keys.ContentSearch):
+				return m, m.setContentSearch(!m.search.active)
 
 				// Handle Ctrl+J for navigating down in filtered list.
-			case tea.KeyCtrlJ:
+			case key.Matches(msg, m.keys.FilterDown):
 				m.list.CursorDown()
 				return m, nil
 
 				// Handle Ctrl+K for navigating up in filtered list.
-			case tea.KeyCtrlK:
+			case key.Matches(msg, m.keys.FilterUp):
 				m.list.CursorUp()
 				return m, nil
 
-				// Handle Ctrl+M for toggling selection in filtered list.
-			case tea.KeyCtrlM:
+				// Handle Ctrl+M (enter) for toggling selection in filtered list.
+			case key.Matches(msg, m.keys.FilterToggle):
 				// Ensure list is not empty and an item is focused before proceeding.
 				if len(m.list.Items()) > 0 && m.list.Index() >= 0 {
 					if currentItem, ok := m.list.SelectedItem().(item); ok {
+						// Unchecking a file selected only by a pattern excludes it from the patterns.
+						if m.patternMatched[currentItem.name] && !m.selected[currentItem.name] {
+							return m, m.excludeFromPatterns(currentItem.name)
+						}
 						// Toggle the selection state directly in the main `selected` map.
 						// The list item's visual state (checkbox) is updated by the delegate reading this map.
+						before := m.snapshotSelection()
 						m.selected[currentItem.name] = !m.selected[currentItem.name]
+						m.recordUndo(toggleDescription(currentItem.name, m.selected[currentItem.name]), before)
 					}
 				}
 				return m, nil
 
+				// Handle backspace to delete from the filter query.
+			case msg.Type == tea.KeyBackspace:
+				if len(m.filterQuery) > 0 {
+					// Use rune-aware slicing to correctly handle multi-byte characters.
+					m.filterQuery = string([]rune(m.filterQuery)[:len([]rune(m.filterQuery))-1])
+					return m, m.queryChanged()
+				}
+				return m, nil
+
 				// Handle printable characters (runes) and spacebar for building the filter query.
-				// IMPORTANT: This case must come *after* checking specific keys like Ctrl+J/K/M.
-			case tea.KeyRunes, tea.KeySpace:
-				// Don't add navigation runes (j,k) to the query if they might be used for nav fallback.
-				// However, since we explicitly handle Ctrl+J/K, allowing j/k here is likely fine
-				// and expected if the user wants to search for filenames containing 'j' or 'k'.
+				// IMPORTANT: This case must come *after* the filter-mode bindings, which may be
+				// bound to printable keys too.
+			case msg.Type == tea.KeyRunes, msg.Type == tea.KeySpace:
 				m.filterQuery += string(msg.Runes)
-				m.applyFilter()
-				return m, nil
+				return m, m.queryChanged()
 
-				// NOTE: The explicit 'case tea.KeyEnter:' was removed to fix duplicate case error.
-				// Enter is handled by the key.Matches(msg, m.keys.Confirm) check below.
-			default:
-				// If msg.Type didn't match special keys above, check against defined bindings.
-				// This handles 'y' and 'enter' for confirmation correctly.
-				if key.Matches(msg, m.keys.Confirm) {
-					m.copyStarted = true
-					m.statusMessage = "Processing files..."
-					var filesToCopy []string
-					// Confirm always uses the full selection map, regardless of current filter.
-					for relativePath, selected := range m.selected {
-						if selected {
-							filesToCopy = append(filesToCopy, relativePath)
-						}
-					}
-					copyCmd := m.performCopyAndSave(filesToCopy)
-					cmds = append(cmds, copyCmd)
-					return m, tea.Batch(cmds...)
-				}
+				// Keys of Confirm that are neither query text nor filter-mode bindings confirm
+				// right away; with the default bindings enter toggles instead.
+			case key.Matches(msg, m.keys.Confirm):
+				// Confirm always uses the full selection map, regardless of current filter.
+				cmds = append(cmds, m.confirmSelection())
+				return m, tea.Batch(cmds...)
 			}
 			// --- End Filtering Mode Specific Key Handling ---
 
 			// --- Not Filtering Mode Logic ---
 		} else {
+			// In visual mode the range keys take precedence; navigation moves the range end.
+			if m.visual.active {
+				if cmd, handled := m.updateVisual(msg); handled {
+					return m, cmd
+				}
+			}
 			switch {
+			// Start a visual range selection ('v', 'V').
+			case key.Matches(msg, m.keys.Visual):
+				m.startVisual()
+				return m, nil
+
 			// Enter filtering mode when '/' is pressed.
 			case key.Matches(msg, m.keys.StartFilter):
+				if m.visual.active {
+					m.stopVisual() // Filtering replaces the items the range refers to.
+				}
 				m.isFiltering = true
 				m.filterQuery = ""
-				// Update the keys shown in the full help view.
-				m.list.AdditionalFullHelpKeys = func() []key.Binding {
-					// Show only Esc and Confirm/Quit in help when filtering.
-					return []key.Binding{m.keys.ClearFilter, m.keys.Confirm, m.keys.Quit}
+				if m.prefillFilter {
+					// The first filter after start continues with the query of the last session.
+					m.filterQuery = m.lastFilterQuery
+					m.prefillFilter = false
 				}
+				// Update the keys shown in the full help view.
+				m.list.AdditionalFullHelpKeys = m.keys.filterHelpKeys
 				m.list.Select(0)
 				// Apply empty filter initially; this updates title and prepares prompt display.
 				m.applyFilter()
-				m.list.Title = fmt.Sprintf("Filter results for '%s':", m.filterQuery)
+				m.list.Title = fmt.Sprintf("Filter results for '%s'%s:", m.filterQuery, m.sortTitleSuffix())
 				return m, nil
 
+			// Enter filtering mode searching file contents (ctrl+g); it starts with an empty query.
+			case key.Matches(msg, m.keys.ContentSearch):
+				if m.visual.active {
+					m.stopVisual()
+				}
+				m.isFiltering = true
+				m.filterQuery = ""
+				m.list.AdditionalFullHelpKeys = m.keys.filterHelpKeys
+				m.list.Select(0)
+				return m, m.setContentSearch(true)
+
 				// Handle normal mode selection toggle ('space' or 'm').
 			case key.Matches(msg, m.keys.Toggle):
 				if len(m.list.Items()) > 0 && m.list.Index() >= 0 {
 					if currentItem, ok := m.list.SelectedItem().(item); ok {
 						relativePath := currentItem.name
+						// Unchecking a file selected only by a pattern excludes it from the patterns.
+						if m.patternMatched[relativePath] && !m.selected[relativePath] {
+							return m, m.excludeFromPatterns(relativePath)
+						}
 						isSelected := m.selected[relativePath]
+						before := m.snapshotSelection()
 						m.selected[relativePath] = !isSelected
+						m.recordUndo(toggleDescription(relativePath, !isSelected), before)
 
 						// Check if a hidden path was just deselected.
 						pathContainsHidden := false
@@ -489,31 +1082,61 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, timerCmd)
 				return m, tea.Batch(cmds...)
 
-				// Handle clearing selection ('c' or 'C').
-			case key.Matches(msg, m.keys.ClearSelected):
-				clear(m.selected)
-				m.refreshListItems()
-				// Set status message and timer.
-				m.statusMessage = "Clear Selected"
-				if m.statusTimer != nil {
-					m.statusTimer.Stop()
+				// Open the named selection set picker ('s').
+			case key.Matches(msg, m.keys.Sets):
+				m.lastErr = nil
+				m.openSetPicker()
+				return m, nil
+
+				// Open the selection pattern editor ('p').
+			case key.Matches(msg, m.keys.Patterns):
+				m.lastErr = nil
+				m.patternEditor.active = true
+				m.patternEditor.cursor = 0
+				return m, nil
+
+				// Open the copy history panel ('H').
+			case key.Matches(msg, m.keys.History):
+				m.lastErr = nil
+				m.openHistoryPanel()
+				return m, nil
+
+				// Show or hide the detail columns ('i').
+			case key.Matches(msg, m.keys.Details):
+				m.setShowDetails(!m.showDetails)
+				return m, nil
+
+				// Cycle the sort mode ('o').
+			case key.Matches(msg, m.keys.Sort):
+				return m, m.setStatus(m.cycleSortMode())
+
+				// Undo and redo selection changes ('u', 'ctrl+r').
+			case key.Matches(msg, m.keys.Undo):
+				return m, m.undo()
+			case key.Matches(msg, m.keys.Redo):
+				return m, m.redo()
+
+				// Save the selection without copying ('w').
+			case key.Matches(msg, m.keys.Save):
+				// Like a copy, saving must not silently overwrite changes made by another instance.
+				if m.openMergePromptOnConflict() {
+					m.merge.saveOnly = true
+					return m, nil
 				}
-				timerCmd := clearStatusCmd(2 * time.Second)
-				cmds = append(cmds, timerCmd)
-				return m, tea.Batch(cmds...)
+				return m, m.saveSelection()
+
+				// Open the team preset picker ('P').
+			case key.Matches(msg, m.keys.Presets):
+				m.openPresetPicker()
+				return m, nil
+
+				// Clear the explicit selection ('c' or 'C').
+			case key.Matches(msg, m.keys.ClearSelected):
+				return m, m.clearSelection()
 
 				// Handle confirming selection ('y' or 'enter').
 			case key.Matches(msg, m.keys.Confirm):
-				m.copyStarted = true
-				m.statusMessage = "Processing files..."
-				var filesToCopy []string
-				for relativePath, selected := range m.selected {
-					if selected {
-						filesToCopy = append(filesToCopy, relativePath)
-					}
-				}
-				copyCmd := m.performCopyAndSave(filesToCopy)
-				cmds = append(cmds, copyCmd)
+				cmds = append(cmds, m.confirmSelection())
 				return m, tea.Batch(cmds...)
 			}
 		}
@@ -541,6 +1164,28 @@ func (m model) View() string {
 	if m.quitting {
 		return docStyle.Render("Exiting...")
 	}
+	// The selection set picker replaces the list while open.
+	if m.picker.active {
+		return m.viewSetPicker()
+	}
+	if m.remap.active {
+		return m.viewRemapPrompt()
+	}
+	if m.merge.active && !m.copyStarted {
+		return m.viewMergePrompt()
+	}
+	if m.patternEditor.active {
+		return m.viewPatternEditor()
+	}
+	if m.history.active {
+		return m.viewHistoryPanel()
+	}
+	if m.presetPicker.active {
+		return m.viewPresetPicker()
+	}
+	if m.quitPrompt.active && !m.copyStarted {
+		return m.viewQuitPrompt()
+	}
 
 	// --- Prepare Info/Status/Filter Line ---
 	// This line appears below the list view.
@@ -548,21 +1193,43 @@ func (m model) View() string {
 	if m.isFiltering {
 		// When filtering, show the filter prompt and current query.
 		prompt := filterPromptStyle.Render("Filter: ")
+		if m.search.active {
+			prompt = filterPromptStyle.Render("Search: ")
+		}
 		// Display query + a simulated cursor using an underscore.
 		infoLine = prompt + m.filterQuery + helpStyle.Render("_")
+		if m.search.active {
+			infoLine += helpStyle.Render("  (" + m.searchProgress() + ")")
+		}
+		// Feedback of actions taken while filtering (bulk changes, undo) follows the query.
+		if m.statusMessage != "" {
+			infoLine += helpStyle.Render("  " + m.statusMessage)
+		}
 	} else if m.copyStarted {
 		// Show persistent message while copying.
 		infoLine = helpStyle.Render("Processing files...")
+	} else if m.lastErr != nil {
+		// Show the last non-fatal error until the next action.
+		infoLine = errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr))
+	} else if m.visual.active {
+		// Show the visual mode indicator and its keys while a range is selected.
+		infoLine = filterPromptStyle.Render("-- VISUAL -- ") +
+			helpStyle.Render(fmt.Sprintf("%d file(s) • %s", len(m.visualRange()), keyHints(m.keys.VisualToggle, m.keys.VisualSelect, m.keys.VisualDeselect, m.keys.VisualExit)))
 	} else if m.statusMessage != "" {
 		// Show temporary status message.
 		infoLine = helpStyle.Render(m.statusMessage)
 	}
 	// Optionally, add default help text if no other message is present.
 	if infoLine == "" {
-		infoLine = helpStyle.Render("Press ? for help, / to filter")
+		infoLine = helpStyle.Render(fmt.Sprintf("Press %s for help, %s to filter", m.keys.ShowHelp.Help().Key, m.keys.StartFilter.Help().Key))
 	}
 
 	listView := m.list.View()
+	// With the preview shown, pin the list to its width so the preview does not shift.
+	if listWidth, previewWidth := m.previewWidths(m.width - docStyle.GetHorizontalFrameSize()); previewWidth > 0 {
+		listView = lipgloss.NewStyle().Width(listWidth).Render(lipgloss.NewStyle().MaxWidth(listWidth).Render(listView))
+		listView = lipgloss.JoinHorizontal(lipgloss.Top, listView, m.viewPreview(previewWidth))
+	}
 	return docStyle.Render(listView + "\n" + infoLine)
 }
 
@@ -571,12 +1238,29 @@ func (m model) View() string {
 // delegate implements list.ItemDelegate to customize how items are rendered in the list.
 type delegate struct {
 	selected *map[string]bool // Pointer to the model's selection map (shared state).
+	matched  *map[string]bool // Pointer to the model's pattern match map (shared state).
+	stats    *fileStatCache   // File metadata for detail rows (shared state).
+	details  bool             // Flag to render size, age, line count and token estimate columns.
+	anchor   int              // List index of the visual range anchor (-1 outside visual mode).
+	query    filterQuery      // Filter query whose matched characters are highlighted (nil if none).
 }
 
 // newItemDelegate creates a new instance of our custom delegate.
-func newItemDelegate(selected *map[string]bool) delegate {
+func newItemDelegate(selected *map[string]bool, matched *map[string]bool, stats *fileStatCache) delegate {
 	// We perform all custom rendering logic within the Render method.
-	return delegate{selected: selected}
+	return delegate{selected: selected, matched: matched, stats: stats, anchor: -1}
+}
+
+// updateDelegate replaces the delegate after a change of how rows are rendered (details,
+// visual mode), since the list keeps its own copy.
+func (m *model) updateDelegate() {
+	d := newItemDelegate(&m.selected, &m.patternMatched, m.fileStats)
+	d.details = m.showDetails
+	d.query = m.filterTerms
+	if m.visual.active {
+		d.anchor = m.visualAnchor()
+	}
+	m.list.SetDelegate(d)
 }
 
 // Height returns the number of terminal lines a single item should occupy.
@@ -600,13 +1284,71 @@ func (d delegate) Render(w io.Writer, m list.Model, index int, listItem list.Ite
 	relativePath := i.Title()
 	isSelected := (*d.selected)[relativePath] // Check selection status via the shared map pointer.
 
-	// Determine checkbox string and apply style if checked.
-	checkbox := "[ ] "
+	// Rows of the visual range get a background; each styled part carries it, since the
+	// styles of the parts would otherwise end it.
+	inRange := d.anchor >= 0 && index >= min(d.anchor, m.Index()) && index <= max(d.anchor, m.Index())
+	rangeStyle := func(style lipgloss.Style) lipgloss.Style {
+		if inRange {
+			return style.Inherit(visualStyle)
+		}
+		return style
+	}
+
+	// Determine checkbox string and apply style if checked. Files selected only through a
+	// pattern get a distinct marker, so they can be told apart from explicit checks.
+	checkbox := rangeStyle(itemStyle).Render("[ ] ")
 	if isSelected {
-		checkbox = checkedStyle.Render("[x] ")
+		checkbox = rangeStyle(checkedStyle).Render("[x] ")
+	} else if (*d.matched)[relativePath] {
+		checkbox = rangeStyle(patternStyle).Render("[*] ")
 	}
 
-	line := checkbox + relativePath
+	// Without colours (NO_COLOR, high-contrast theme) the cursor row is marked with '>' and the
+	// rest of the visual range with '│', so both can be told by their characters alone.
+	if markRows {
+		marker := "  "
+		if index == m.Index() {
+			marker = "> "
+		} else if inRange {
+			marker = "│ "
+		}
+		checkbox = rangeStyle(itemStyle).Render(marker) + checkbox
+	}
+
+	// Fit the row into the list width: detail columns are right-aligned, and long paths lose
+	// their middle rather than the file name.
+	// The path of the focused row carries the cursor style itself; styling the whole line would
+	// not reach past the reset of the coloured checkbox.
+	pathStyle := rangeStyle(itemStyle)
+	if index == m.Index() {
+		pathStyle = rangeStyle(selectedStyle)
+	}
+	// While filtering, the characters matched by the query are highlighted, so it is clear why
+	// a path is listed.
+	renderPath := func(width int) string {
+		if d.query == nil {
+			return pathStyle.Render(truncateMiddle(relativePath, width))
+		}
+		return highlightPath(relativePath, width, d.query.highlights(relativePath), pathStyle, matchStyle.Inherit(pathStyle))
+	}
+	line := checkbox + pathStyle.Render(relativePath)
+	if width := m.Width() - lipgloss.Width(checkbox); width > 0 {
+		line = checkbox + renderPath(width)
+		if i.hit != nil {
+			// Content search results show their matches instead of the details; the path gets up
+			// to half of the row.
+			path := truncateMiddle(relativePath, max(width/2, 10))
+			if hitWidth := width - lipgloss.Width(path) - 2; hitWidth > 0 {
+				line = checkbox + pathStyle.Render(path+"  ") + rangeStyle(detailStyle).Render(truncateEnd(contentHitColumn(i.hit), hitWidth))
+			}
+		} else if d.details {
+			columns := detailColumns(d.stats.withLines(relativePath), time.Now())
+			if pathWidth := width - lipgloss.Width(columns) - 1; pathWidth >= 10 {
+				padding := strings.Repeat(" ", pathWidth-lipgloss.Width(truncateMiddle(relativePath, pathWidth))+1)
+				line = checkbox + renderPath(pathWidth) + pathStyle.Render(padding) + rangeStyle(detailStyle).Render(columns)
+			}
+		}
+	}
 
 	// Apply styling based on whether the item is currently focused (cursor position).
 	if index == m.Index() {
@@ -621,9 +1363,10 @@ func (d delegate) Render(w io.Writer, m list.Model, index int, listItem list.Ite
 
 // loadFilesAndSelectionRecursive performs a recursive directory scan starting from targetDir,
 // loads the previous selection state from the persistence file (.yank),
-// and validates the loaded selections against the files found.
+// and validates the loaded selections against the files found. Saved paths that no longer
+// exist but whose file was moved are returned as renames, to be offered for re-mapping.
 // It ignores ".git" directories and the root persistence file itself.
-func loadFilesAndSelectionRecursive(targetDir string) (availableFiles []string, selectedFiles []string, err error) {
+func loadFilesAndSelectionRecursive(targetDir string) (availableFiles []string, state selectionState, renames []remapCandidate, err error) {
 	availableFiles = make([]string, 0)
 	validFileMap := make(map[string]struct{}) // Set to efficiently track relative paths found during scan.
 
@@ -669,8 +1412,10 @@ func loadFilesAndSelectionRecursive(targetDir string) (availableFiles []string,
 				return nil
 			}
 
-			// Exclude the persistence file *only* if it's located directly in the target directory.
-			if relativePath == persistenceDotFileName {
+			// Exclude the persistence file (and its transient lock and temporary files)
+			// *only* if it's located directly in the target directory.
+			if relativePath == persistenceDotFileName || relativePath == persistenceDotFileName+".lock" ||
+				strings.HasPrefix(relativePath, persistenceDotFileName+".tmp-") {
 				// Double check it's the root one, not a file with the same name deeper
 				if filepath.Dir(path) == targetDir {
 					return nil
@@ -688,149 +1433,153 @@ func loadFilesAndSelectionRecursive(targetDir string) (availableFiles []string,
 	if walkErr != nil {
 		err = fmt.Errorf("error during directory walk: %w", walkErr)
 		// Return any files found before the error and the error itself.
-		return availableFiles, []string{}, err
+		return availableFiles, newSelectionState(), nil, err
 	}
 
 	// --- Load and Validate Previous Selections ---
-	persistenceFilePath := getPersistenceFilePath(targetDir) // Path to ".yank" in the root targetDir.
-	content, readErr := os.ReadFile(persistenceFilePath)
+	state, readErr := readSelectionState(targetDir)
 	if readErr != nil {
-		// If the persistence file simply doesn't exist, return successfully with no previous selections.
-		if errors.Is(readErr, os.ErrNotExist) {
-			return availableFiles, []string{}, nil
-		}
-		// Report other errors encountered while reading the persistence file.
-		err = fmt.Errorf("reading persistence file '%s': %w", persistenceFilePath, readErr)
 		// Return files found and the read error.
-		return availableFiles, []string{}, err
-	}
-
-	// Process the content of the persistence file (one relative path per line).
-	loadedLines := strings.Split(string(content), "\n")
-	selectedFiles = make([]string, 0)
-	for _, line := range loadedLines {
-		trimmedRelativePath := strings.TrimSpace(line)
-		// Only process non-empty lines.
-		if trimmedRelativePath != "" {
-			// Only keep selections that correspond to files actually found during the recent walk.
-			// This automatically handles files that might have been deleted or moved since last run.
-			if _, exists := validFileMap[trimmedRelativePath]; exists {
-				selectedFiles = append(selectedFiles, trimmedRelativePath)
-			} else {
-				// Log if a previously selected file is no longer found.
-				log.Printf("Note: Previously selected file '%s' not found during walk, removing from list.", trimmedRelativePath)
-			}
-		}
+		return availableFiles, state, nil, readErr
 	}
 
-	return availableFiles, selectedFiles, nil
-}
-
-// saveSelections saves the provided list of selected relative paths to the persistence file
-// located in the target directory root. If the list is empty, it removes the file.
-func saveSelections(relativePaths []string, targetDir string) error {
-	filePath := getPersistenceFilePath(targetDir) // Path to ".yank" in root.
-
-	// If the current selection is empty, remove the persistence file to clean up.
-	if len(relativePaths) == 0 {
-		err := os.Remove(filePath)
-		// Report error only if it's *not* "file doesn't exist" (which is fine).
-		if err != nil && !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("failed remove persistence file '%s': %w", filePath, err)
+	// Look for the new location of saved paths (in the selection or any set) that have moved.
+	var stale []string
+	for _, relativePath := range state.referencedPaths() {
+		if _, exists := validFileMap[relativePath]; !exists {
+			stale = append(stale, relativePath)
 		}
-		return nil
+	}
+	renames = findRenames(targetDir, state, stale, availableFiles)
+	movedTo := make(map[string]string, len(renames))
+	for _, c := range renames {
+		movedTo[c.oldPath] = c.newPath
 	}
 
-	// Write the selected relative paths, one per line, using standard newline characters.
-	content := strings.Join(relativePaths, "\n")
-	// Write file with rw-r----- permissions.
-	err := os.WriteFile(filePath, []byte(content), 0640)
-	if err != nil {
-		return fmt.Errorf("failed write persistence file '%s': %w", filePath, err)
+	// Only keep selections that correspond to files actually found during the recent walk.
+	// This automatically handles files that might have been deleted or moved since last run.
+	// Named sets are validated when they are loaded.
+	validSelection := make([]string, 0, len(state.current))
+	for _, relativePath := range state.current {
+		if _, exists := validFileMap[relativePath]; exists {
+			validSelection = append(validSelection, relativePath)
+		} else if _, moved := movedTo[relativePath]; !moved {
+			// Log if a previously selected file is no longer found (moved files are offered for re-mapping).
+			log.Printf("Note: Previously selected file '%s' not found during walk, removing from list.", relativePath)
+		}
 	}
-	return nil
+	state.current = validSelection
+
+	return availableFiles, state, renames, nil
 }
 
-// copyToClipboard attempts to copy the given text to the system clipboard
-// using OS-specific commands. Currently supports macOS, Linux (xclip/xsel), Windows.
-func copyToClipboard(text string) error {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "darwin":
-		// Assume pbcopy is available on macOS.
-		cmd = exec.Command("pbcopy")
-
-	case "linux":
-		// Prefer xclip if available.
-		xclipPath, err := exec.LookPath("xclip")
-		if err == nil {
-			cmd = exec.Command(xclipPath, "-selection", "clipboard") // Use primary clipboard selection
-		} else {
-			// Fallback to xsel if xclip is not found.
-			xselPath, err := exec.LookPath("xsel")
-			if err == nil {
-				cmd = exec.Command(xselPath, "--clipboard", "--input") // Arguments for clipboard input
-			} else {
-				// Neither tool found, provide instructions and return error.
-				log.Println("Clipboard error: requires 'xclip' or 'xsel'. Please install one via your package manager (e.g., 'sudo apt install xclip').")
-				return fmt.Errorf("clipboard dependency missing: requires 'xclip' or 'xsel'")
-			}
+// saveSelections saves the provided list of selected relative paths and patterns as the current
+// selection in the persistence file located in the target directory root. If activeSet names a
+// selection set, that set is updated as well. Other named sets are preserved; if nothing remains
+// to be persisted, the file is removed. If the saved selection no longer matches base, another
+// instance saved meanwhile and errSelectionConflict is returned without writing.
+func saveSelections(relativePaths []string, patterns []string, activeSet string, session sessionState, targetDir string, base saveBase) error {
+	_, err := updateSelectionState(targetDir, func(state *selectionState) error {
+		if base.conflicts(*state) {
+			return errSelectionConflict
 		}
+		state.current = relativePaths
+		state.patterns = patterns
+		if activeSet != "" {
+			state.sets[activeSet] = relativePaths
+			state.setPatterns[activeSet] = patterns
+		}
+		state.session = session
+		recordFingerprints(state, targetDir, relativePaths)
+		return nil
+	})
+	return err
+}
 
-	case "windows":
-		// Use clip.exe on Windows.
-		cmd = exec.Command("clip.exe")
-
-	default:
-		// Log content start and return an error for unsupported platforms.
-		log.Printf("Clipboard not supported on this OS (%s). Content starts with:\n%.80s...\n", runtime.GOOS, text)
-		return fmt.Errorf("clipboard OS unsupported: %s", runtime.GOOS)
+// sessionSnapshot captures the UI state that is persisted alongside the selection.
+func (m *model) sessionSnapshot() sessionState {
+	session := sessionState{
+		ShowHidden:   m.showHidden,
+		OutputFormat: outputFormatPlain,
+		ActiveSet:    m.activeSet,
+		ActivePreset: m.activePreset,
+		SortMode:     m.sortMode,
+		Details:      m.showDetails,
 	}
-
-	return runClipboardCommand(cmd, text)
+	session.FilterQuery = m.lastFilterQuery
+	if m.isFiltering && m.filterQuery != "" && !m.search.active {
+		session.FilterQuery = m.filterQuery
+	}
+	if currentItem, ok := m.list.SelectedItem().(item); ok {
+		session.Cursor = currentItem.name
+	}
+	if m.copyHTML {
+		session.OutputFormat = outputFormatHTML
+	}
+	return session
 }
 
-// runClipboardCommand executes a given clipboard command (like pbcopy, xclip, clip.exe)
-// by piping the provided text to its standard input. Reduces code repetition.
-func runClipboardCommand(cmd *exec.Cmd, text string) error {
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		// Use filepath.Base for a cleaner command name in the error message.
-		return fmt.Errorf("failed get stdin pipe for %s: %w", filepath.Base(cmd.Path), err)
+// restoreSession re-applies the UI state of the previous session; the last filter query is
+// pre-filled when filter mode is first entered. Entries referring to sets
+// or files that no longer exist are ignored. The output format is applied in main, where
+// it is known whether -html was given explicitly.
+func (m *model) restoreSession(session sessionState) {
+	m.showHidden = session.ShowHidden
+	if _, exists := m.state.sets[session.ActiveSet]; exists {
+		m.activeSet = session.ActiveSet
 	}
-
-	// Write the text to the command's stdin in a separate goroutine.
-	// This avoids potential deadlocks if the text buffer is very large.
-	go func() {
-		// IMPORTANT: Ensure stdin is closed when writing is done or if an error occurs.
-		// This signals EOF to the receiving command.
-		defer stdin.Close()
-		_, err := io.WriteString(stdin, text)
-		if err != nil {
-			// Log errors from the goroutine, as returning them directly is complex.
-			log.Printf("Error writing to %s stdin: %v", filepath.Base(cmd.Path), err)
+	if _, exists := m.presets.find(session.ActivePreset); exists && m.activeSet == "" {
+		m.activePreset = session.ActivePreset
+	}
+	m.setShowDetails(session.Details)
+	if _, known := sortModeLabels[session.SortMode]; known {
+		m.sortMode = session.SortMode
+	}
+	m.lastFilterQuery = session.FilterQuery
+	m.prefillFilter = session.FilterQuery != ""
+	m.refreshListItems()
+
+	for i, listItem := range m.list.Items() {
+		if li, ok := listItem.(item); ok && li.name == session.Cursor {
+			m.list.Select(i)
+			break
 		}
-	}()
-
-	// CombinedOutput captures both stdout and stderr, which is useful for debugging command failures.
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// If the command failed (non-zero exit status), log its output and return a wrapped error.
-		log.Printf("%s command failed. Output:\n%s", filepath.Base(cmd.Path), string(output))
-		return fmt.Errorf("%s command failed: %w", filepath.Base(cmd.Path), err)
 	}
-
-	return nil
 }
 
 // --- Async Task for Copying ---
 
+// confirmSelection starts copying the selection, unless another yank instance changed the
+// saved selection since it was loaded; then the merge prompt is opened instead.
+func (m *model) confirmSelection() tea.Cmd {
+	if m.openMergePromptOnConflict() {
+		return nil
+	}
+	return m.startCopy()
+}
+
+// startCopy marks the copy as started and returns the command copying the full selection.
+func (m *model) startCopy() tea.Cmd {
+	m.copyStarted = true
+	m.lastErr = nil
+	m.statusMessage = "Processing files..."
+	return m.performCopyAndSave(m.effectivePaths())
+}
+
 // performCopyAndSave is executed as a tea.Cmd (in a separate goroutine by Bubble Tea)
 // to handle the potentially time-consuming tasks of reading file metadata and content,
 // aggregating it, copying to the clipboard, and saving the final selection state,
 // without blocking the main UI thread. It sends a tea.Quit message when finished.
 func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
+	// Capture the UI state now; the model keeps changing while the command runs.
+	session := m.sessionSnapshot()
+	// Only explicit checks are saved as paths; pattern matches are re-evaluated on load.
+	explicitPaths := m.selectedPaths()
+	patterns := slices.Clone(m.patterns)
+	base := m.saveBase()
+	historyMode := m.historyMode
+	preamble := strings.TrimSpace(m.activePreamble())
+
 	// Return the function that Bubble Tea will execute asynchronously.
 	return func() tea.Msg {
 		startTime := time.Now()
@@ -839,6 +1588,18 @@ func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
 		readErrors := 0                                 // Count files that couldn't be read.
 		statErrors := 0                                 // Count files whose metadata couldn't be retrieved.
 		copyErrCount := 0                               // Track if the final clipboard operation failed.
+		var htmlBuilder *htmlBundleBuilder              // Rich HTML rendering, only built when copyHTML is set.
+		var copiedPaths []string                        // Files whose content made it into the bundle, in order.
+		if m.copyHTML && !m.htmlUnavailable {
+			htmlBuilder = newHTMLBundleBuilder()
+		}
+		// A preset's preamble introduces the files, e.g. with instructions for the reader.
+		if preamble != "" {
+			contentBuilder.WriteString(preamble + "\n\n")
+			if htmlBuilder != nil {
+				htmlBuilder.addPreamble(preamble)
+			}
+		}
 
 		// --- Read Files and Aggregate Content ---
 		for _, relativePath := range relativePathsToCopy {
@@ -875,28 +1636,59 @@ func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
 			contentBuilder.WriteString(header)
 			contentBuilder.Write(fileContent)
 			contentBuilder.WriteString("\n\n") // Add a blank line separator between files.
+			if htmlBuilder != nil {
+				htmlBuilder.addFile(relativePath, header, fileContent)
+			}
+			copiedPaths = append(copiedPaths, relativePath)
 		}
 
 		// --- Copy Aggregated Content to Clipboard ---
 		combinedContent := contentBuilder.String()
 		var copyErr error
+		richNote := "" // Set when HTML was requested but the backend could only take plain text.
 		// Calculate how many files were successfully processed (had metadata and content read).
 		filesSuccessfullyProcessed := len(relativePathsToCopy) - readErrors - statErrors
 		// Attempt clipboard copy only if there's actual content gathered.
 		if filesSuccessfullyProcessed > 0 {
-			copyErr = copyToClipboard(combinedContent)
+			richCopied := false // Whether the HTML rendering made it onto the clipboard.
+			if htmlBuilder != nil {
+				htmlContent := htmlBuilder.String()
+				copyErr = copyRichToClipboard(combinedContent, htmlContent)
+				if errors.Is(copyErr, errRichUnsupported) {
+					richNote = fmt.Sprintf(" (%v)", copyErr)
+					copyErr = nil
+				} else if copyErr == nil {
+					richCopied = true
+					// Optionally read the clipboard back to catch silent truncation by the backend.
+					if m.verifyCopy {
+						copyErr = verifyRichClipboard(combinedContent, htmlContent)
+					}
+				}
+			} else {
+				copyErr = copyToClipboard(combinedContent)
+			}
+			if copyErr == nil && m.verifyCopy && !richCopied {
+				copyErr = verifyClipboard(combinedContent)
+			}
 			if copyErr != nil {
 				copyErrCount++
 			}
 		} else if len(relativePathsToCopy) > 0 {
 			// Log if files were selected, but none could be successfully read/processed.
-			log.Printf(logPrefix + "Skip clipboard: No content could be read/processed.")
+			log.Print(logPrefix + "Skip clipboard: No content could be read/processed.")
 		}
 
 		// --- Save Final Selection State ---
-		// Save the list of relative paths that were *intended* for copying (the selection state),
+		// Save the selection state (explicit paths and patterns) that was *intended* for copying,
 		// regardless of whether reading/copying operations were fully successful.
-		saveErr := saveSelections(relativePathsToCopy, m.targetDir)
+		saveErr := saveSelections(explicitPaths, patterns, m.activeSet, session, m.targetDir, base)
+
+		// --- Record Copy History ---
+		// A history failure never fails the copy; it is only mentioned in the final log line.
+		var historyErr error
+		if copyErr == nil && filesSuccessfullyProcessed > 0 && historyMode != historyModeOff {
+			_, historyErr = recordHistory(m.targetDir, copiedPaths, combinedContent, htmlBuilder != nil, historyMode == historyModeFull)
+		}
 
 		// --- Log Final Status Summary ---
 		logMsg := "" // Accumulate status message components for the final log line.
@@ -917,7 +1709,10 @@ func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
 		if copyErrCount == 0 && saveErr == nil { // If no critical clipboard or save errors occurred
 			if len(relativePathsToCopy) > 0 { // And files were actually selected
 				if filesSuccessfullyProcessed > 0 { // And some files were successfully processed
-					logMsg = fmt.Sprintf("Copied %d file(s), saved selection.", filesSuccessfullyProcessed)
+					logMsg = fmt.Sprintf("Copied %d file(s)%s, saved selection.", filesSuccessfullyProcessed, richNote)
+					if historyErr != nil {
+						logMsg += fmt.Sprintf(" History Error: %v.", historyErr)
+					}
 				} else { // Files were selected, but none could be read/processed
 					logMsg = fmt.Sprintf("Saved selection (%d), but no content read/processed.", len(relativePathsToCopy))
 				}
@@ -928,6 +1723,20 @@ func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
 			logMsg = "Completed with errors."
 		}
 
+		// A failed clipboard copy is reported in the TUI (the alt screen would hide a log line),
+		// keeping the application open so the user can fix the environment and retry.
+		if copyErr != nil {
+			failErr := fmt.Errorf("clipboard: %w", copyErr)
+			if saveErr != nil {
+				failErr = errors.Join(failErr, saveErr)
+			}
+			return copyFailedMsg{err: failErr}
+		}
+		// Another instance saved meanwhile; the TUI asks how to resolve it before saving.
+		if errors.Is(saveErr, errSelectionConflict) {
+			return saveConflictMsg{copiedFiles: filesSuccessfullyProcessed}
+		}
+
 		// Print the final consolidated log message with the task duration.
 		// Uses the standard log package, output appears cleanly after the TUI exits.
 		log.Printf(logPrefix+"%s (%.2fs)", logMsg, time.Since(startTime).Seconds())
@@ -939,44 +1748,158 @@ func (m *model) performCopyAndSave(relativePathsToCopy []string) tea.Cmd {
 
 // --- Helper Function ---
 
-// getPersistenceFilePath constructs the absolute path for the persistence file
+// getPersistenceFilePath constructs the absolute path for the persistence file, either
+// inside targetDir or in the state directory, depending on stateMode.
 func getPersistenceFilePath(targetDir string) string {
-	return filepath.Join(targetDir, persistenceDotFileName)
+	if stateMode == stateModeTree {
+		return treeStateFilePath(targetDir)
+	}
+	filePath, err := xdgStateFilePath(targetDir)
+	if err != nil {
+		// main verifies the state directory at startup, so this is only a last resort.
+		return treeStateFilePath(targetDir)
+	}
+	return filePath
 }
 
 // --- Main Function ---
 
-// printHelp displays the command-line usage instructions for the tool.
-func printHelp() {
+// printHelp displays the command-line usage instructions for the tool, listing the
+// effective keybindings.
+func printHelp(keys keyMap) {
 	fmt.Printf("%s: TUI File Copier\n\n", appName)
 	fmt.Println(`Recursively scans a directory, allows interactive file selection, and copies the relative path, metadata (modification time, size), and content of selected files to the clipboard.`)
 	fmt.Println("\nUsage:")
-	fmt.Printf("  %s [-dir <directory>] [-h|-help]\n", appName)
+	fmt.Printf("  %s [-dir <directory>] [-set <name>|-preset <name>] [-state tree|xdg|xdg-git] [-history meta|full|off] [-save-on-quit] [-html] [-verify] [-h|-help]\n", appName)
+	fmt.Printf("  %s unyank [-dir <directory>] [-in <file>|-] [-y] [-n]\n", appName)
+	fmt.Printf("  %s doctor [-dir <directory>] [-state tree|xdg|xdg-git] [-no-roundtrip]\n", appName)
+	fmt.Printf("  %s history [list|show|cat|copy|restore|clear] [<id>] [-dir <directory>] [-n <count>]\n", appName)
 	fmt.Println("\nOptions:")
 	flag.PrintDefaults()
-	fmt.Println("\nKeybindings (within the TUI):")
+	fmt.Println("\nSubcommands:")
+	fmt.Println("  unyank             Parse a bundle (from the clipboard, a file, or stdin), show a diff per file,")
+	fmt.Println("                       and write the approved files back into the directory.")
+	fmt.Println("  doctor             Report clipboard backends (with a round-trip test), terminal capabilities,")
+	fmt.Println("                       config files, and the selection state of the directory.")
+	fmt.Println("  history            List past copies; show, print (cat) or re-copy an entry's exact bytes,")
+	fmt.Println("                       or restore its file list as the saved selection.")
+	configPath, err := userConfigPath()
+	if err != nil {
+		configPath = filepath.Join("~", ".config", appName, userConfigFileName)
+	}
+	fmt.Printf("\nKeybindings (within the TUI; override them in the [keys] table of %s):\n", configPath)
+	// row prints the keys and description of an action; actions unbound by the config are left out.
+	// Long key lists get a line of their own.
+	row := func(keys string, lines ...string) {
+		if keys == "" {
+			return
+		}
+		if utf8.RuneCountInString(keys) > 18 {
+			fmt.Println("  " + keys)
+			keys = ""
+		}
+		fmt.Printf("  %-18s %s\n", keys, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Println("                       " + line)
+		}
+	}
 	fmt.Println("  --- Normal Mode ---")
-	fmt.Println("  j, k, ↓, ↑         Move cursor up/down.")
-	fmt.Println("  space, m,          Toggle selection for the focused file/path.")
-	fmt.Println("  c, C,              Clear selection.")
-	fmt.Println("  .                  Toggle visibility of hidden files/directories (paths containing '.').")
-	fmt.Println("                       Selected hidden items remain visible.")
-	fmt.Println("  /                  Enter filter mode (fuzzy search).")
-	fmt.Println("  y, enter           Confirm selection, copy data to clipboard, save selection, and quit.")
-	fmt.Println("  q, ctrl+c          Quit without copying.")
-	fmt.Println("  ?                  Show/hide the built-in help view for more keys.")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Move cursor down/up.")
+	row(keyColumn(keys.PrevPage), "Show the previous page.")
+	row(keyColumn(keys.NextPage), "Show the next page.")
+	row(keyColumn(keys.GoToStart, keys.GoToEnd), "Go to the first/last file.")
+	row(keyColumn(keys.Toggle), "Toggle selection for the focused file/path.")
+	row(keyColumn(keys.ClearSelected), "Clear the selected files (patterns are kept; edit them with "+keyColumn(keys.Patterns)+").")
+	row(keyColumn(keys.SelectVisible, keys.DeselectVisible), "Select/deselect all visible files (the results while filtering).")
+	row(keyColumn(keys.InvertVisible), "Invert the selection of all visible files.")
+	row(keyColumn(keys.SelectMatches), "Select all matches of the filter query (the last one in normal mode).")
+	row(keyColumn(keys.Visual), "Start a visual range at the focused file; move the cursor to extend it.")
+	row(keyColumn(keys.Undo, keys.Redo), "Undo/redo the last selection change (toggle, clear, set/preset load, ...).")
+	row(keyColumn(keys.Sets), "Open the named selection sets picker (load, save as, rename, delete).")
+	row(keyColumn(keys.Patterns), "Open the selection pattern editor (glob patterns such as internal/**, !**/*_test.go).")
+	row(keyColumn(keys.History), "Open the copy history of the directory (restore a selection, copy again).")
+	row(keyColumn(keys.Presets), fmt.Sprintf("Open the team presets defined in a committed '%s' file.", presetsFileName))
+	row(keyColumn(keys.ToggleHidden), "Toggle visibility of hidden files/directories (paths containing '.').",
+		"Selected hidden items remain visible.")
+	row(keyColumn(keys.Details), "Show/hide detail columns: size, age, line count, and estimated tokens (~4 bytes each).")
+	row(keyColumn(keys.Sort), "Cycle the sort mode: path, file name, extension, modification time (newest",
+		"first), size (largest first), selected first. Also orders equally good",
+		"filter matches; remembered with the session.")
+	row(keyColumn(keys.Preview), "Show/hide the syntax-highlighted preview of the focused file.")
+	row(keyColumn(keys.PreviewDown, keys.PreviewUp), "Scroll the preview down/up by a line.")
+	row(keyColumn(keys.PreviewPgDown, keys.PreviewPgUp), "Scroll the preview down/up by half a page (also in filter mode).")
+	row(keyColumn(keys.StartFilter), "Enter filter mode (fuzzy search).")
+	row(keyColumn(keys.ContentSearch), "Enter filter mode searching file contents (text, case-insensitive unless it",
+		"contains capitals); rows show the number of matching lines and the first one.")
+	row(keyColumn(keys.Confirm), "Confirm selection, copy data to clipboard, save selection, and quit.")
+	row(keyColumn(keys.Save), "Save the selection (and patterns) without copying.")
+	row(keyColumn(keys.Quit), "Quit without copying; asks first if the selection has unsaved changes.")
+	row(keyColumn(keys.ShowHelp), "Show/hide the built-in help view for more keys.")
 	fmt.Println("\n  --- Filter Mode ---")
-	fmt.Println("  (type)             Enter text to filter list (fuzzy search).")
-	fmt.Println("  esc                Exit filter mode and clear filter.")
-	fmt.Println("  ctrl+j, ctrl+k     Move cursor down/up within filtered list.")
-	fmt.Println("  ctrl+m             Toggle selection for the focused file in filtered list.")
-	fmt.Println("  backspace          Delete last character from filter query.")
-	fmt.Println("  y, enter           Confirm selection (based on overall checks), copy, save, and quit.")
-	fmt.Println("  q, ctrl+c          Quit without copying.")
-
+	row("(type)", "Enter text to filter list (fuzzy search; space-separated terms must all match).",
+		"Operators: ext:go,ts  dir:internal/  'exact  ^prefix  suffix$  re:/regexp/  !negate",
+		"Results are ranked like fzf (word and segment starts, file names first); matches are highlighted.")
+	row(keyColumn(keys.ClearFilter), "Exit filter mode and clear filter.")
+	row(keyColumn(keys.ContentSearch), "Switch between matching paths and searching contents.")
+	row(keyColumn(keys.FilterDown, keys.FilterUp), "Move cursor down/up within filtered list.")
+	row(keyColumn(keys.FilterToggle), "Toggle selection for the focused file in filtered list.")
+	row("backspace", "Delete last character from filter query.")
+	row(keyColumn(keys.SelectVisible, keys.DeselectVisible), "Select/deselect all results.")
+	row(keyColumn(keys.InvertVisible, keys.SelectMatches), "Invert the results / select all matches.")
+	row(keyColumn(keys.FilterClearSelected), "Clear the selection of the results only.")
+	row(keyColumn(keys.FilterSort), "Cycle the sort mode (orders equally good matches and content matches).")
+	row(keyColumn(keys.FilterUndo, keys.FilterRedo), "Undo/redo the last selection change.")
+	row(keyColumn(keys.filterConfirmKeys()), "Confirm selection (based on overall checks), copy, save, and quit.")
+	row(keyColumn(keys.Quit), "Quit without copying (asks first if there are unsaved changes).")
+	fmt.Println("\n  --- Visual Mode (after pressing " + keyColumn(keys.Visual) + ") ---")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Extend or shrink the range (it runs from the start to the cursor; the",
+		"other navigation keys move the cursor too).")
+	row(keyColumn(keys.VisualToggle), "Toggle the range: deselect it if fully selected, otherwise select it.")
+	row(keyColumn(keys.VisualSelect), "Select all files in the range.")
+	row(keyColumn(keys.VisualDeselect), "Deselect all files in the range.")
+	row(keyColumn(keys.VisualExit), "Leave visual mode without changes.")
+	fmt.Println("\n  --- Selection Sets (after pressing " + keyColumn(keys.Sets) + ") ---")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Move cursor down/up.")
+	row(keyColumn(keys.PickerLoad), "Load the focused set as the current selection.")
+	row(keyColumn(keys.PickerSaveAs), "Save the current selection as a named set.")
+	row(keyColumn(keys.PickerRename), "Rename the focused set.")
+	row(keyColumn(keys.PickerDelete), "Delete the focused set (asks for confirmation).")
+	row(keyColumn(keys.PickerClose), "Close the picker.")
+	fmt.Println("\n  --- Selection Patterns (after pressing " + keyColumn(keys.Patterns) + ") ---")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Move cursor down/up.")
+	row(keyColumn(keys.PatternsAdd), "Add a pattern (prefix with ! to exclude).")
+	row(keyColumn(keys.PatternsDelete), "Delete the focused pattern.")
+	row(keyColumn(keys.PatternsClose), "Close the editor.")
+	fmt.Println("\n  --- Copy History (after pressing " + keyColumn(keys.History) + ") ---")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Move cursor down/up.")
+	row(keyColumn(keys.HistoryDetails), "Show/hide the files of the focused entry.")
+	row(keyColumn(keys.HistoryRestore), "Restore the entry's files as the selection.")
+	row(keyColumn(keys.HistoryRecopy), "Copy the entry's exact bytes to the clipboard again.")
+	row(keyColumn(keys.HistoryClose), "Close the panel.")
+	fmt.Println("\n  --- Team Presets (after pressing " + keyColumn(keys.Presets) + ") ---")
+	row(keyColumn(keys.CursorDown, keys.CursorUp), "Move cursor down/up.")
+	row(keyColumn(keys.PresetLoad), "Load the focused preset (replaces the selection and patterns).")
+	row(keyColumn(keys.PresetClose), "Close the picker.")
+	fmt.Println("\n  --- Moved Files (prompt shown on start) ---")
+	row(keyColumn(keys.RemapAccept), "Re-map saved paths to the files' new locations.")
+	row(keyColumn(keys.RemapDecline), "Drop the missing paths from the selection.")
+	fmt.Println("\n  --- Unsaved Changes (prompt shown on quit) ---")
+	row(keyColumn(keys.QuitSave), "Save the selection and quit.")
+	row(keyColumn(keys.QuitDiscard), "Discard the changes and quit (a second ctrl+c does the same).")
+	row(keyColumn(keys.QuitCancel), "Return to the list.")
+	fmt.Println("\n  --- Selection Changed on Disk (prompt shown on confirm or save) ---")
+	row(keyColumn(keys.MergeOverwrite), "Overwrite it with your selection and copy (or save).")
+	row(keyColumn(keys.MergeCombine), "Apply the changes from disk to your selection.")
+	row(keyColumn(keys.MergeTheirs), "Take the selection from disk.")
+	row(keyColumn(keys.MergeCancel), "Cancel.")
 	fmt.Println("\nFeatures:")
 	fmt.Println("  - Recursive Scan: Finds files in all subdirectories (incl. hidden, excluding .git).")
-	fmt.Printf("  - Persistence: Remembers the last selection for each directory in a '%s' file.\n", persistenceDotFileName)
+	fmt.Printf("  - Persistence: Remembers the last selection and named selection sets for each directory in a '%s' file.\n", persistenceDotFileName)
+	fmt.Printf("  - Team Presets: Named selections with an optional prompt preamble, shared in a committed '%s' file.\n", presetsFileName)
+	fmt.Println("  - Content Search: Finds the files containing a text, with results shown as they are found.")
+	fmt.Println("  - Preview: A split view shows the focused file with syntax highlighting, scrollable on its own.")
+	fmt.Println("  - Themes: dark, light and high-contrast palettes with per-role colour overrides; NO_COLOR is honoured.")
+	fmt.Println("  - Rename Tracking: Saved paths of moved files are found via git or their content and offered for re-mapping.")
 	fmt.Println("  - Clipboard Format: Each file's data is preceded by a header:")
 	fmt.Println("    --- FILENAME: path/to/file.txt | Modified: YYYY-MM-DD HH:MM:SS | Size: NNN bytes ---")
 	fmt.Printf("  - Exclusions: Ignores '.git' directories and the root '%s' state file.\n", persistenceDotFileName)
@@ -987,8 +1910,34 @@ func main() {
 	// Logs will appear after the TUI exits.
 	log.SetFlags(0)
 
+	// --- Load User Configuration ---
+	// The theme applies to the subcommands as well; an invalid file falls back to the defaults
+	// there (doctor reports it) and stops the TUI below.
+	config, configErr := loadUserConfig()
+	applyTheme(config.theme())
+
+	// --- Subcommand Dispatch ---
+	// Subcommands use their own flag sets and never start the TUI.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "unyank":
+			os.Exit(runUnyank(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		}
+	}
+
 	// --- Command-Line Flag Parsing ---
 	dir := flag.String("dir", ".", "Directory to list files from")
+	copyHTML := flag.Bool("html", false, "Also copy a syntax-highlighted HTML rendering (text/html) for rich editors (with wl-copy/xclip the clipboard holds text/html only and plain text goes to the primary selection; plain text only with xsel)")
+	verifyCopy := flag.Bool("verify", false, "Read the clipboard back after copying and report a mismatch")
+	setName := flag.String("set", "", "Start with the named selection set instead of the last selection")
+	presetName := flag.String("preset", "", "Start with the named team preset from "+presetsFileName+" instead of the last selection")
+	saveOnQuit := flag.Bool("save-on-quit", false, "Save unsaved selection changes when quitting instead of asking")
+	historyFlag := flag.String("history", historyModeMeta, "What to record in the copy history: meta (files and hash only), full (also the copied bytes) or off; default from the config file")
+	stateFlag := flag.String("state", defaultStateMode(), "Where to store selection state: tree (.yank in the directory), xdg ($XDG_STATE_HOME/yank, keyed by path) or xdg-git (keyed by git remote and path); default from $YANK_STATE")
 	// Use a separate variable for boolean flags to easily check their value *after* parsing.
 	var showHelp bool
 	// Define the primary flag (-help) and its shorthand (-h), both modifying the same variable.
@@ -998,11 +1947,19 @@ func main() {
 	flag.Parse()
 
 	// --- Handle Help Flag ---
-	// Check if the user requested help via either -h or -help.
+	// Check if the user requested help via either -h or -help. The help lists the effective keys.
 	if showHelp {
-		printHelp()
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v (showing the default keys)\n\n", configErr)
+		}
+		printHelp(config.keyMap())
 		os.Exit(0) // Exit the program cleanly with status 0 (success).
 	}
+	// Key overrides are validated up front, so conflicting bindings never reach the TUI.
+	if configErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", configErr)
+		os.Exit(1)
+	}
 
 	// --- Process Directory Argument ---
 	// Resolve the potentially relative directory path provided by the user (or default ".")
@@ -1019,9 +1976,99 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --- Resolve State Location ---
+	if err := validateStateMode(*stateFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := validateHistoryMode(*historyFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Keeping the copied bytes is opt-in: without -history, the mode comes from the config file.
+	historyMode := *historyFlag
+	historyExplicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "history" {
+			historyExplicit = true
+		}
+	})
+	if !historyExplicit && config.History != "" {
+		historyMode = config.History
+	}
+	stateMode = *stateFlag
+	if stateMode != stateModeTree {
+		if _, err := xdgStateDir(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Move an existing in-tree .yank to the state directory on first use of an out-of-tree mode.
+	// The move is reported on stderr and, since the TUI hides that, in the status line.
+	migratedTo, err := migrateTreeState(targetDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if migratedTo != "" {
+		log.Printf("Note: moved '%s' to '%s'.", treeStateFilePath(targetDir), migratedTo)
+	}
+
 	// --- Start TUI Application ---
 	// Create the initial application model, passing the validated target directory.
-	m := initialModel(targetDir)
+	m := initialModel(targetDir, config.keyMap())
+	// An explicit -html wins over the output format remembered from the last session.
+	m.copyHTML = *copyHTML || m.state.session.OutputFormat == outputFormatHTML
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "html" {
+			m.copyHTML = *copyHTML
+		}
+	})
+	// Backends that cannot hold HTML at all (xsel) fall back to plain text. The status line
+	// says so whether HTML was asked for by -html or by the remembered session, and copyHTML
+	// stays set so the next save keeps the remembered output format.
+	if m.copyHTML {
+		if backend, err := checkClipboard(); err == nil && backend.richCopy == nil {
+			m.statusMessage = fmt.Sprintf("HTML output: %s cannot hold HTML; copying plain text only", backend.name)
+			m.htmlUnavailable = true
+		} else if err == nil && backend.richNote != "" {
+			// wl-copy and xclip hold one format per selection; say which one the clipboard gets.
+			m.statusMessage = fmt.Sprintf("HTML output with %s: %s", backend.name, backend.richNote)
+		}
+	}
+	if migratedTo != "" {
+		note := fmt.Sprintf("Moved '%s' to '%s'", persistenceDotFileName, migratedTo)
+		if m.statusMessage != "" {
+			note += "; " + m.statusMessage
+		}
+		m.statusMessage = note
+	}
+	m.verifyCopy = *verifyCopy
+	m.historyMode = historyMode
+	m.saveOnQuit = *saveOnQuit
+	if *setName != "" && m.err == nil {
+		if err := m.loadSet(*setName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (available: %s)\n", err, strings.Join(m.state.setNames(), ", "))
+			os.Exit(1)
+		}
+	}
+	if *presetName != "" && m.err == nil {
+		if *setName != "" {
+			fmt.Fprintln(os.Stderr, "Error: -set and -preset cannot be combined")
+			os.Exit(1)
+		}
+		if _, err := m.loadPreset(*presetName); err != nil {
+			if m.lastErr != nil {
+				err = m.lastErr // The presets file itself could not be read.
+			} else if m.presets.path == "" {
+				err = fmt.Errorf("%w (no %s found)", err, presetsFileName)
+			} else {
+				err = fmt.Errorf("%w (available: %s)", err, strings.Join(m.presets.names(), ", "))
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Create and run the Bubble Tea program.
 	// Using WithAltScreen provides a better user experience by restoring the original
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Fuzzy Matching ---
+
+// Scores of the fuzzy matcher, following fzf: every matched character scores, gaps cost, and
+// characters at the start of a word or path segment earn a bonus, so "mgo" prefers main.go to
+// migrations/go.sum. Matches in the file name earn a little extra, since that is what people
+// usually type.
+const (
+	scoreMatch             = 16
+	scoreGapStart          = -3
+	scoreGapExtension      = -1
+	bonusBoundary          = 8 // After a non-word character such as '_', '-' or '.'.
+	bonusBoundaryDelimiter = 9 // After '/', the start of a path segment.
+	bonusNonWord           = 8 // The non-word character itself.
+	bonusCamel             = 7 // camelCase humps and the first digit of a number.
+	bonusConsecutive       = 4 // Minimum bonus of a character following a matched one.
+	bonusFirstCharFactor   = 2 // The bonus of the first pattern character counts double.
+	bonusBasename          = 2 // Per character matched in the file name.
+
+	noScore = -1 << 30 // Marks impossible alignments.
+)
+
+// charClass groups characters for the bonus computation.
+type charClass int
+
+const (
+	charDelimiter charClass = iota // '/' (and the start of the path).
+	charNonWord                    // Punctuation and white space.
+	charLower
+	charUpper
+	charNumber
+)
+
+// classOf returns the class of a character.
+func classOf(r rune) charClass {
+	switch {
+	case r == '/':
+		return charDelimiter
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsDigit(r):
+		return charNumber
+	case unicode.IsLetter(r):
+		return charLower // Letters without case, e.g. CJK.
+	}
+	return charNonWord
+}
+
+// bonusAt returns the bonus of matching a character of class class after one of class prev.
+func bonusAt(prev, class charClass) int {
+	if class > charNonWord {
+		switch prev {
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+	if prev == charLower && class == charUpper || prev != charNumber && class == charNumber {
+		return bonusCamel
+	}
+	if class == charNonWord || class == charDelimiter {
+		return bonusNonWord
+	}
+	return 0
+}
+
+// fuzzyMatch finds the best alignment of pattern (lower-cased runes) in text, case-insensitively,
+// and returns its score (higher is better) and the rune positions of the matched characters.
+// It is the dynamic programming variant of fzf's algorithm: among all ways of matching the
+// characters in order it picks the one scoring highest, not merely the first one.
+func fuzzyMatch(pattern []rune, text string) (score int, positions []int, ok bool) {
+	runes := []rune(text)
+	n, m := len(runes), len(pattern)
+	if m == 0 || m > n {
+		return 0, nil, m == 0
+	}
+	lower := make([]rune, n)
+	for j, r := range runes {
+		lower[j] = unicode.ToLower(r)
+	}
+
+	// Skip the table for texts not containing the pattern as a subsequence, i.e. most of them.
+	i := 0
+	for j := 0; j < n && i < m; j++ {
+		if lower[j] == pattern[i] {
+			i++
+		}
+	}
+	if i < m {
+		return 0, nil, false
+	}
+
+	// Bonus per position, and where the file name starts.
+	bonus := make([]int, n)
+	prev := charDelimiter
+	for j, r := range runes {
+		class := classOf(r)
+		bonus[j] = bonusAt(prev, class)
+		prev = class
+	}
+	basename := strings.LastIndexByte(text, '/') + 1
+	basename = len([]rune(text[:basename]))
+
+	// best[i*n+j] is the best score of pattern[:i+1] with pattern[i] matched at j; chunk holds
+	// the bonus of the run of consecutive matches ending there, from the previous match.
+	best := make([]int, m*n)
+	chunk := make([]int, m*n)
+	from := make([]int, m*n)
+	for j := range n {
+		best[j] = noScore
+		if lower[j] == pattern[0] {
+			best[j] = scoreMatch + bonus[j]*bonusFirstCharFactor
+			chunk[j] = bonus[j]
+			from[j] = -1
+			if j >= basename {
+				best[j] += bonusBasename
+			}
+		}
+	}
+	for i := 1; i < m; i++ {
+		row, prevRow := best[i*n:(i+1)*n], best[(i-1)*n:i*n]
+		// gap is the best score of the previous character matched at k <= j-2, less the
+		// extension penalty for every character skipped after the first.
+		gap, gapFrom := noScore, -1
+		for j := range n {
+			row[j] = noScore
+			if j >= 2 {
+				if gap != noScore {
+					gap += scoreGapExtension
+				}
+				if prevRow[j-2] > gap {
+					gap, gapFrom = prevRow[j-2], j-2
+				}
+			}
+			if lower[j] != pattern[i] || j < i {
+				continue
+			}
+			matchScore := scoreMatch
+			if j >= basename {
+				matchScore += bonusBasename
+			}
+			if prevRow[j-1] != noScore {
+				// A run of consecutive matches keeps the bonus of its first character.
+				consecutive := max(bonus[j], chunk[(i-1)*n+j-1], bonusConsecutive)
+				row[j] = prevRow[j-1] + matchScore + consecutive
+				chunk[i*n+j] = consecutive
+				from[i*n+j] = j - 1
+			}
+			if gap != noScore {
+				if score := gap + scoreGapStart + matchScore + bonus[j]; score > row[j] {
+					row[j] = score
+					chunk[i*n+j] = bonus[j]
+					from[i*n+j] = gapFrom
+				}
+			}
+		}
+	}
+
+	// Take the best end position and walk back through the table.
+	end := -1
+	lastRow := best[(m-1)*n:]
+	for j := range n {
+		if lastRow[j] != noScore && (end < 0 || lastRow[j] > lastRow[end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions = make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i*n+j]
+	}
+	return lastRow[end], positions, true
+}
+
+// highlightPath renders a path shortened like truncateMiddle to width cells, with the runes
+// flagged in matched drawn in matchStyle and the others in style.
+func highlightPath(relativePath string, width int, matched []bool, style, matchStyle lipgloss.Style) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(relativePath)
+	head, tail := truncateMiddleRunes(relativePath, width)
+	var b strings.Builder
+	render := func(from, to int) {
+		for start := from; start < to; {
+			end := start
+			for end < to && matched[end] == matched[start] {
+				end++
+			}
+			if matched[start] {
+				b.WriteString(matchStyle.Render(string(runes[start:end])))
+			} else {
+				b.WriteString(style.Render(string(runes[start:end])))
+			}
+			start = end
+		}
+	}
+	render(0, head)
+	if head+tail < len(runes) {
+		b.WriteString(style.Render("…"))
+	}
+	render(len(runes)-tail, len(runes))
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{pattern: "", text: "main.go", ok: true},
+		{pattern: "mgo", text: "main.go", ok: true, positions: []int{0, 5, 6}},
+		{pattern: "mgo", text: "MAIN.GO", ok: true, positions: []int{0, 5, 6}},
+		{pattern: "main", text: "domain/main.go", ok: true, positions: []int{7, 8, 9, 10}},
+		{pattern: "hdl", text: "api/handler.go", ok: true, positions: []int{4, 7, 8}},
+		{pattern: "ogm", text: "main.go", ok: false},
+		{pattern: "main.go.x", text: "main.go", ok: false},
+		{pattern: "é", text: "café/menü.txt", ok: true, positions: []int{3}},
+		{pattern: "ü", text: "café/menü.txt", ok: true, positions: []int{8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" in "+tt.text, func(t *testing.T) {
+			_, positions, ok := fuzzyMatch([]rune(tt.pattern), tt.text)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !slices.Equal(positions, tt.positions) {
+				t.Errorf("positions = %v, want %v", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	// Each pair lists the better match first.
+	tests := []struct {
+		pattern       string
+		better, worse string
+	}{
+		{pattern: "mgo", better: "main.go", worse: "migrations/go.sum"},
+		{pattern: "main", better: "main.go", worse: "domain.go"},
+		{pattern: "api", better: "internal/api/server.go", worse: "internal/rapid.go"},
+		{pattern: "ut", better: "user/UserTable.go", worse: "user/output.go"},
+		{pattern: "conf", better: "src/config.go", worse: "config/src/x.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			betterScore, _, ok := fuzzyMatch([]rune(tt.pattern), tt.better)
+			if !ok {
+				t.Fatalf("%q does not match %q", tt.pattern, tt.better)
+			}
+			worseScore, _, ok := fuzzyMatch([]rune(tt.pattern), tt.worse)
+			if !ok {
+				t.Fatalf("%q does not match %q", tt.pattern, tt.worse)
+			}
+			if betterScore <= worseScore {
+				t.Errorf("score of %q = %d, not above %d of %q", tt.better, betterScore, worseScore, tt.worse)
+			}
+		})
+	}
+}
+
+func TestFilterQueryHighlights(t *testing.T) {
+	tests := []struct {
+		query string
+		path  string
+		want  string // 'x' for highlighted runes, '.' for the others.
+	}{
+		{query: "", path: "main.go", want: "......."},
+		{query: "mgo", path: "main.go", want: "x....xx"},
+		{query: "ext:go", path: "api/a.go", want: ".....xxx"},
+		{query: "dir:api", path: "src/api/a.go", want: "....xxx....."},
+		{query: "^src main.go$", path: "src/main.go", want: "xxx.xxxxxxx"},
+		{query: "'é !ext:md", path: "café/ü.txt", want: "...x......"},
+		{query: "re:[0-9]+", path: "v12.go", want: ".xx..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseFilterQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []rune
+			for _, matched := range q.highlights(tt.path) {
+				if matched {
+					got = append(got, 'x')
+				} else {
+					got = append(got, '.')
+				}
+			}
+			if string(got) != tt.want {
+				t.Errorf("highlights(%q) = %s, want %s", tt.path, string(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Merge Prompt ---
+
+// mergePrompt holds the overlay shown when the saved selection was changed on disk (e.g., by
+// another yank instance) between loading it and confirming a copy.
+type mergePrompt struct {
+	active          bool     // Flag indicating whether the prompt replaces the file list.
+	theirs          []string // Current selection as found on disk (existing files only, sorted).
+	theirsPatterns  []string // Patterns as found on disk.
+	added           []string // Paths selected on disk but not in the loaded selection.
+	removed         []string // Paths in the loaded selection but no longer selected on disk.
+	patternsAdded   []string // Patterns on disk but not in the loaded ones.
+	patternsRemoved []string // Patterns loaded but no longer on disk.
+	saveOnly        bool     // Flag set when the prompt was opened by saving instead of copying.
+	quitAfterSave   bool     // Flag set when overwriting saves and then quits (copy done, or quitting).
+	copiedFiles     int      // Number of files already on the clipboard when the save found the conflict.
+}
+
+// errSelectionConflict aborts a save that would overwrite a selection saved by another yank
+// instance since this one read it.
+var errSelectionConflict = errors.New("the saved selection was changed by another yank instance")
+
+// saveBase is what a save expects to find on disk. The comparison runs while the persistence
+// file is locked, so no other instance can save between the check and the write.
+type saveBase struct {
+	paths        []string            // Selection as last read from or written to disk (sorted).
+	patterns     []string            // Patterns as last read from or written to disk.
+	minePaths    []string            // Selection being saved (sorted); finding it on disk is no conflict.
+	minePatterns []string            // Patterns being saved.
+	available    map[string]struct{} // Scanned files; saved entries of other files are ignored, like the loader does.
+}
+
+// saveBase captures the base and the current selection for a save; it is safe to use from a
+// command running in another goroutine.
+func (m *model) saveBase() saveBase {
+	return saveBase{
+		paths:        slices.Clone(m.baseSelection),
+		patterns:     slices.Clone(m.basePatterns),
+		minePaths:    sortedCopy(m.selectedPaths()),
+		minePatterns: slices.Clone(m.patterns),
+		available:    fileSet(m.allAvailableFiles),
+	}
+}
+
+// existing returns the paths that are among the scanned files, sorted.
+func (b saveBase) existing(paths []string) []string {
+	return existingIn(b.available, paths)
+}
+
+// conflicts reports whether the state on disk differs from the base, unless both sides
+// arrived at the same selection and patterns.
+func (b saveBase) conflicts(disk selectionState) bool {
+	theirs := b.existing(disk.current)
+	if slices.Equal(theirs, b.paths) && slices.Equal(disk.patterns, b.patterns) {
+		return false
+	}
+	return !slices.Equal(theirs, b.minePaths) || !slices.Equal(disk.patterns, b.minePatterns)
+}
+
+// setBase records the selection and patterns as last read from or written to disk.
+func (m *model) setBase(paths, patterns []string) {
+	m.baseSelection = paths
+	m.basePatterns = slices.Clone(patterns)
+}
+
+// openMergePromptOnConflict compares the saved selection and patterns on disk with the ones
+// loaded on start and opens the merge prompt if they were changed meanwhile. It reports
+// whether it did so. This check only avoids starting a copy that cannot be saved; the save
+// itself repeats it under the lock.
+func (m *model) openMergePromptOnConflict() bool {
+	disk, err := readSelectionState(m.targetDir)
+	if err != nil {
+		return false // Unreadable state is reported by the save itself.
+	}
+	base := m.saveBase()
+	theirs := base.existing(disk.current)
+	if !base.conflicts(disk) {
+		// Either nothing changed, or both sides arrived at the same selection.
+		m.setBase(theirs, disk.patterns)
+		return false
+	}
+
+	prompt := mergePrompt{active: true, theirs: theirs, theirsPatterns: slices.Clone(disk.patterns)}
+	prompt.added, prompt.removed = listDiff(m.baseSelection, theirs)
+	prompt.patternsAdded, prompt.patternsRemoved = listDiff(m.basePatterns, disk.patterns)
+	m.merge = prompt
+	return true
+}
+
+// openMergePromptForSave opens the merge prompt after a save found a conflict, so overwriting
+// saves (and quits if quitAfterSave). If the conflict is gone by now, it reports false.
+func (m *model) openMergePromptForSave(quitAfterSave bool, copiedFiles int) bool {
+	if !m.openMergePromptOnConflict() {
+		return false
+	}
+	m.quitPrompt = quitPrompt{} // The merge prompt takes over when saving on quit.
+	m.merge.saveOnly = true
+	m.merge.quitAfterSave = quitAfterSave
+	m.merge.copiedFiles = copiedFiles
+	return true
+}
+
+// listDiff returns the entries of theirs missing in base, and those of base missing in theirs.
+func listDiff(base, theirs []string) (added, removed []string) {
+	for _, entry := range theirs {
+		if !slices.Contains(base, entry) {
+			added = append(added, entry)
+		}
+	}
+	for _, entry := range base {
+		if !slices.Contains(theirs, entry) {
+			removed = append(removed, entry)
+		}
+	}
+	return added, removed
+}
+
+// existingPaths returns the paths that are among the scanned files, sorted.
+func (m *model) existingPaths(paths []string) []string {
+	return existingIn(fileSet(m.allAvailableFiles), paths)
+}
+
+// fileSet returns the set of the given paths.
+func fileSet(paths []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(paths))
+	for _, relativePath := range paths {
+		set[relativePath] = struct{}{}
+	}
+	return set
+}
+
+// existingIn returns the paths that are in available, sorted.
+func existingIn(available map[string]struct{}, paths []string) []string {
+	existing := make([]string, 0, len(paths))
+	for _, relativePath := range sortedCopy(paths) {
+		if _, exists := available[relativePath]; exists {
+			existing = append(existing, relativePath)
+		}
+	}
+	return existing
+}
+
+// updateMergePrompt handles key presses while the merge prompt is shown.
+func (m model) updateMergePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.MergeOverwrite):
+		prompt := m.merge
+		m.setBase(prompt.theirs, prompt.theirsPatterns)
+		m.merge = mergePrompt{}
+		switch {
+		case prompt.quitAfterSave:
+			return m, m.saveAndQuit(prompt.copiedFiles)
+		case prompt.saveOnly:
+			return m, m.saveSelection()
+		}
+		return m, m.startCopy()
+
+	case key.Matches(msg, m.keys.MergeCombine):
+		// Apply the changes made on disk on top of the own, unsaved changes.
+		before := m.snapshotSelection()
+		for _, relativePath := range m.merge.added {
+			m.selected[relativePath] = true
+		}
+		for _, relativePath := range m.merge.removed {
+			delete(m.selected, relativePath)
+		}
+		for _, pattern := range m.merge.patternsAdded {
+			if !slices.Contains(m.patterns, pattern) {
+				m.patterns = append(m.patterns, pattern)
+			}
+		}
+		m.patterns = slices.DeleteFunc(m.patterns, func(pattern string) bool {
+			return slices.Contains(m.merge.patternsRemoved, pattern)
+		})
+		m.setBase(m.merge.theirs, m.merge.theirsPatterns)
+		m.merge = mergePrompt{}
+		m.recomputePatternMatches()
+		m.recordUndo("merge selection from disk", before)
+		return m, m.setStatus("Merged the selection from disk; review and confirm again")
+
+	case key.Matches(msg, m.keys.MergeTheirs):
+		before := m.snapshotSelection()
+		clear(m.selected)
+		for _, relativePath := range m.merge.theirs {
+			m.selected[relativePath] = true
+		}
+		m.patterns = slices.Clone(m.merge.theirsPatterns)
+		m.setBase(m.merge.theirs, m.merge.theirsPatterns)
+		m.merge = mergePrompt{}
+		m.recomputePatternMatches()
+		m.recordUndo("take selection from disk", before)
+		return m, m.setStatus("Loaded the selection from disk; review and confirm again")
+
+	case key.Matches(msg, m.keys.MergeCancel):
+		m.merge = mergePrompt{}
+	}
+	return m, nil
+}
+
+// viewMergePrompt renders the merge prompt in place of the file list.
+func (m model) viewMergePrompt() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("The saved selection was changed by another yank instance:") + "\n\n")
+	for _, relativePath := range m.merge.added {
+		sb.WriteString(diffAddStyle.Render("  + "+relativePath) + "\n")
+	}
+	for _, relativePath := range m.merge.removed {
+		sb.WriteString(diffRemoveStyle.Render("  - "+relativePath) + "\n")
+	}
+	for _, pattern := range m.merge.patternsAdded {
+		sb.WriteString(diffAddStyle.Render("  + pattern "+pattern) + "\n")
+	}
+	for _, pattern := range m.merge.patternsRemoved {
+		sb.WriteString(diffRemoveStyle.Render("  - pattern "+pattern) + "\n")
+	}
+	sb.WriteString("\n" + helpStyle.Render(fmt.Sprintf("Your selection: %d file(s), on disk: %d file(s)", len(m.selectedPaths()), len(m.merge.theirs))) + "\n")
+	if m.merge.copiedFiles > 0 {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("Copied %d file(s) to the clipboard; the selection is not saved yet.", m.merge.copiedFiles)) + "\n")
+	}
+	action := "copy"
+	switch {
+	case m.merge.quitAfterSave:
+		action = "save, then quit"
+	case m.merge.saveOnly:
+		action = "save"
+	}
+	sb.WriteString(helpStyle.Render(fmt.Sprintf("%s overwrite with mine and %s • %s", m.keys.MergeOverwrite.Help().Key, action, keyHints(m.keys.MergeCombine, m.keys.MergeTheirs, m.keys.MergeCancel))))
+	return docStyle.Render(sb.String())
+}
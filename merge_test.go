@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSaveBaseConflicts(t *testing.T) {
+	// The base was loaded with a.go and b.go selected; this instance now saves a.go and c.go.
+	base := saveBase{
+		paths:        []string{"a.go", "b.go"},
+		patterns:     []string{"internal/**"},
+		minePaths:    []string{"a.go", "c.go"},
+		minePatterns: []string{"internal/**"},
+		available:    fileSet([]string{"a.go", "b.go", "c.go", "d.go"}),
+	}
+
+	tests := []struct {
+		name     string
+		current  []string // Selection on disk.
+		patterns []string // Patterns on disk.
+		want     bool
+	}{
+		{name: "unchanged", current: []string{"a.go", "b.go"}, patterns: []string{"internal/**"}, want: false},
+		{name: "unchanged in another order", current: []string{"b.go", "a.go"}, patterns: []string{"internal/**"}, want: false},
+		{name: "unchanged apart from deleted files", current: []string{"a.go", "gone.go", "b.go"}, patterns: []string{"internal/**"}, want: false},
+		{name: "changed to the same selection", current: []string{"c.go", "a.go"}, patterns: []string{"internal/**"}, want: false},
+		{name: "selection changed", current: []string{"a.go", "d.go"}, patterns: []string{"internal/**"}, want: true},
+		{name: "selection cleared", current: nil, patterns: []string{"internal/**"}, want: true},
+		{name: "patterns changed", current: []string{"a.go", "b.go"}, patterns: []string{"cmd/**"}, want: true},
+		{name: "patterns removed", current: []string{"a.go", "b.go"}, patterns: nil, want: true},
+		{name: "same selection, other patterns", current: []string{"a.go", "c.go"}, patterns: []string{"cmd/**"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := newSelectionState()
+			disk.current = tt.current
+			disk.patterns = tt.patterns
+			if got := base.conflicts(disk); got != tt.want {
+				t.Errorf("conflicts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveBaseConflictsWithoutBase(t *testing.T) {
+	// Nothing was saved when this instance started; another one saved meanwhile.
+	base := saveBase{
+		minePaths: []string{"a.go"},
+		available: fileSet([]string{"a.go", "b.go"}),
+	}
+	disk := newSelectionState()
+	if base.conflicts(disk) {
+		t.Error("an empty state on disk conflicts with an empty base")
+	}
+	disk.current = []string{"b.go"}
+	if !base.conflicts(disk) {
+		t.Error("a selection saved by another instance does not conflict")
+	}
+	disk.current = []string{"a.go"}
+	if base.conflicts(disk) {
+		t.Error("the selection being saved conflicts with itself")
+	}
+}
+
+func TestListDiff(t *testing.T) {
+	added, removed := listDiff([]string{"a.go", "b.go"}, []string{"b.go", "c.go", "d.go"})
+	if want := []string{"c.go", "d.go"}; !slices.Equal(added, want) {
+		t.Errorf("added = %q, want %q", added, want)
+	}
+	if want := []string{"a.go"}; !slices.Equal(removed, want) {
+		t.Errorf("removed = %q, want %q", removed, want)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Pattern Selections ---
+
+// patternExcludePrefix marks a pattern as an exclusion ("!**/*_test.go").
+const patternExcludePrefix = "!"
+
+// parsePattern splits a pattern entry into its doublestar glob and whether it excludes.
+// A trailing slash selects everything below a directory ("internal/auth/" = "internal/auth/**").
+func parsePattern(pattern string) (glob string, exclude bool) {
+	glob, exclude = strings.CutPrefix(pattern, patternExcludePrefix)
+	if strings.HasSuffix(glob, "/") {
+		glob += "**"
+	}
+	return glob, exclude
+}
+
+// validatePattern checks that pattern is a usable include or exclude entry.
+func validatePattern(pattern string) error {
+	glob, _ := parsePattern(strings.TrimSpace(pattern))
+	if glob == "" {
+		return fmt.Errorf("pattern must not be empty")
+	}
+	if !doublestar.ValidatePattern(glob) {
+		return fmt.Errorf("invalid pattern '%s'", pattern)
+	}
+	return nil
+}
+
+// escapeGlob escapes the glob meta characters of a literal path, so it can be used as a pattern.
+func escapeGlob(relativePath string) string {
+	var sb strings.Builder
+	for _, r := range filepath.ToSlash(relativePath) {
+		if strings.ContainsRune(`*?[]{}\`, r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// matchPatterns fills matched with the paths that match at least one include pattern and no
+// exclude pattern. Patterns always use forward slashes, regardless of the OS.
+func matchPatterns(patterns []string, paths []string, matched map[string]bool) {
+	clear(matched)
+	var includes, excludes []string
+	for _, pattern := range patterns {
+		if glob, exclude := parsePattern(pattern); exclude {
+			excludes = append(excludes, glob)
+		} else {
+			includes = append(includes, glob)
+		}
+	}
+	if len(includes) == 0 {
+		return
+	}
+	matchesAny := func(globs []string, slashPath string) bool {
+		for _, glob := range globs {
+			if ok, _ := doublestar.Match(glob, slashPath); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, relativePath := range paths {
+		slashPath := filepath.ToSlash(relativePath)
+		if matchesAny(includes, slashPath) && !matchesAny(excludes, slashPath) {
+			matched[relativePath] = true
+		}
+	}
+}
+
+// recomputePatternMatches re-evaluates the patterns against all scanned files and refreshes
+// the list (or the filter results while filtering).
+func (m *model) recomputePatternMatches() {
+	matchPatterns(m.patterns, m.allAvailableFiles, m.patternMatched)
+	if m.isFiltering {
+		m.applyFilter()
+	} else {
+		m.refreshListItems()
+	}
+}
+
+// effectivePaths returns the explicitly selected paths plus all pattern matches, i.e. the
+// files a copy includes.
+func (m *model) effectivePaths() []string {
+	paths := m.selectedPaths()
+	for relativePath := range m.patternMatched {
+		if !m.selected[relativePath] {
+			paths = append(paths, relativePath)
+		}
+	}
+	return paths
+}
+
+// excludeFromPatterns adds an exclusion for a file that is only selected through a pattern,
+// which is what unchecking such a file means.
+func (m *model) excludeFromPatterns(relativePath string) tea.Cmd {
+	before := m.snapshotSelection()
+	m.patterns = append(m.patterns, patternExcludePrefix+escapeGlob(relativePath))
+	m.recomputePatternMatches()
+	m.recordUndo("exclude "+relativePath, before)
+	return m.setStatus(fmt.Sprintf("Excluded '%s' from patterns", relativePath))
+}
+
+// patternEditor holds the state of the overlay used to add and remove selection patterns.
+type patternEditor struct {
+	active bool            // Flag indicating whether the editor replaces the file list.
+	adding bool            // Flag set while a new pattern is typed.
+	cursor int             // Index of the focused pattern.
+	input  textinput.Model // Input for new patterns.
+}
+
+// newPatternEditor creates a closed editor with an initialised pattern input.
+func newPatternEditor() patternEditor {
+	input := textinput.New()
+	input.Prompt = "Pattern: "
+	input.PromptStyle = filterPromptStyle
+	input.Placeholder = "internal/auth/**  or  !**/*_test.go"
+	input.CharLimit = 200
+	return patternEditor{input: input}
+}
+
+// updatePatternEditor handles key presses while the pattern editor is open.
+func (m model) updatePatternEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if m.patternEditor.adding {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.patternEditor.adding = false
+			m.patternEditor.input.Blur()
+			m.lastErr = nil
+			return m, nil
+		case tea.KeyEnter:
+			pattern := strings.TrimSpace(m.patternEditor.input.Value())
+			if err := validatePattern(pattern); err != nil {
+				m.lastErr = err
+				return m, nil
+			}
+			m.lastErr = nil
+			before := m.snapshotSelection()
+			m.patterns = append(m.patterns, pattern)
+			m.patternEditor.adding = false
+			m.patternEditor.input.Blur()
+			m.patternEditor.cursor = len(m.patterns) - 1
+			m.recomputePatternMatches()
+			m.recordUndo("add pattern "+pattern, before)
+			return m, m.setStatus(fmt.Sprintf("Added pattern '%s' (%d file(s) matched by all patterns)", pattern, len(m.patternMatched)))
+		}
+		var cmd tea.Cmd
+		m.patternEditor.input, cmd = m.patternEditor.input.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.PatternsClose):
+		m.patternEditor.active = false
+
+	case key.Matches(msg, m.list.KeyMap.CursorUp):
+		if m.patternEditor.cursor > 0 {
+			m.patternEditor.cursor--
+		}
+
+	case key.Matches(msg, m.list.KeyMap.CursorDown):
+		if m.patternEditor.cursor < len(m.patterns)-1 {
+			m.patternEditor.cursor++
+		}
+
+	case key.Matches(msg, m.keys.PatternsAdd):
+		m.patternEditor.adding = true
+		m.patternEditor.input.SetValue("")
+		return m, m.patternEditor.input.Focus()
+
+	case key.Matches(msg, m.keys.PatternsDelete):
+		if i := m.patternEditor.cursor; i >= 0 && i < len(m.patterns) {
+			removed := m.patterns[i]
+			before := m.snapshotSelection()
+			m.patterns = append(m.patterns[:i:i], m.patterns[i+1:]...)
+			m.patternEditor.cursor = min(i, max(len(m.patterns)-1, 0))
+			m.recomputePatternMatches()
+			m.recordUndo("remove pattern "+removed, before)
+			return m, m.setStatus(fmt.Sprintf("Removed pattern '%s'", removed))
+		}
+	}
+	return m, nil
+}
+
+// viewPatternEditor renders the pattern editor in place of the file list.
+func (m model) viewPatternEditor() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Selection patterns:") + "\n\n")
+
+	if len(m.patterns) == 0 {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("No patterns yet. Press %s to add one; prefix it with ! to exclude.", m.keys.PatternsAdd.Help().Key)) + "\n")
+	}
+	for i, pattern := range m.patterns {
+		line := pattern
+		if _, exclude := parsePattern(pattern); exclude {
+			line = diffRemoveStyle.Render(pattern)
+		}
+		if i == m.patternEditor.cursor {
+			sb.WriteString(selectedStyle.Render("> ") + line + "\n")
+		} else {
+			sb.WriteString(itemStyle.Render("  ") + line + "\n")
+		}
+	}
+	sb.WriteString("\n" + helpStyle.Render(fmt.Sprintf("%d file(s) matched", len(m.patternMatched))) + "\n")
+
+	if m.patternEditor.adding {
+		sb.WriteString(m.patternEditor.input.View() + "\n")
+		sb.WriteString(helpStyle.Render("enter add • esc cancel"))
+	} else {
+		sb.WriteString(helpStyle.Render(keyHints(m.keys.PatternsAdd, m.keys.PatternsDelete, m.keys.PatternsClose)))
+	}
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	} else if m.statusMessage != "" {
+		sb.WriteString("\n" + helpStyle.Render(m.statusMessage))
+	}
+	return docStyle.Render(sb.String())
+}
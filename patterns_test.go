@@ -0,0 +1,131 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestMatchPatterns(t *testing.T) {
+	paths := []string{
+		"main.go",
+		"main_test.go",
+		"internal/auth/auth.go",
+		"internal/auth/auth_test.go",
+		"internal/db/db.go",
+		"docs/[draft]*.md",
+		"docs/guide.md",
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name:     "no patterns",
+			patterns: nil,
+			want:     nil,
+		},
+		{
+			name:     "only exclusions select nothing",
+			patterns: []string{"!**/*_test.go"},
+			want:     nil,
+		},
+		{
+			name:     "include then exclude",
+			patterns: []string{"**/*.go", "!**/*_test.go"},
+			want:     []string{"internal/auth/auth.go", "internal/db/db.go", "main.go"},
+		},
+		{
+			name:     "exclusion wins regardless of order",
+			patterns: []string{"!internal/auth/**", "internal/**"},
+			want:     []string{"internal/db/db.go"},
+		},
+		{
+			name:     "trailing slash selects the directory",
+			patterns: []string{"internal/auth/"},
+			want:     []string{"internal/auth/auth.go", "internal/auth/auth_test.go"},
+		},
+		{
+			name:     "excluded directory",
+			patterns: []string{"**", "!internal/"},
+			want:     []string{"docs/[draft]*.md", "docs/guide.md", "main.go", "main_test.go"},
+		},
+		{
+			name:     "escaped literal name",
+			patterns: []string{escapeGlob("docs/[draft]*.md")},
+			want:     []string{"docs/[draft]*.md"},
+		},
+		{
+			name:     "unescaped name is a glob",
+			patterns: []string{"docs/*.md"},
+			want:     []string{"docs/[draft]*.md", "docs/guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := map[string]bool{"stale.go": true}
+			matchPatterns(tt.patterns, paths, matched)
+			got := slices.Sorted(maps.Keys(matched))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matched = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		wantGlob    string
+		wantExclude bool
+	}{
+		{pattern: "**/*.go", wantGlob: "**/*.go"},
+		{pattern: "internal/", wantGlob: "internal/**"},
+		{pattern: "!**/*_test.go", wantGlob: "**/*_test.go", wantExclude: true},
+		{pattern: "!vendor/", wantGlob: "vendor/**", wantExclude: true},
+	}
+	for _, tt := range tests {
+		glob, exclude := parsePattern(tt.pattern)
+		if glob != tt.wantGlob || exclude != tt.wantExclude {
+			t.Errorf("parsePattern(%q) = %q, %t; want %q, %t", tt.pattern, glob, exclude, tt.wantGlob, tt.wantExclude)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: "**/*.go"},
+		{pattern: "!internal/"},
+		{pattern: "  cmd/*.go  "},
+		{pattern: "", wantErr: true},
+		{pattern: "   ", wantErr: true},
+		{pattern: "!", wantErr: true},
+		{pattern: "docs/[draft.md", wantErr: true},
+		{pattern: "{a,b", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validatePattern(tt.pattern); (err != nil) != tt.wantErr {
+			t.Errorf("validatePattern(%q) = %v, want error: %t", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "main.go", want: "main.go"},
+		{path: "docs/[draft]*.md", want: `docs/\[draft\]\*.md`},
+		{path: "a/{b}?.txt", want: `a/\{b\}\?.txt`},
+	}
+	for _, tt := range tests {
+		if got := escapeGlob(tt.path); got != tt.want {
+			t.Errorf("escapeGlob(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
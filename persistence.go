@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Selection State Persistence ---
+
+// stateFormatVersion is the version of the structured (JSON) persistence format written by
+// this build. Files with a higher version are refused rather than silently downgraded.
+const stateFormatVersion = 1
+
+// selectionSetHeaderPrefix starts a line that opens a named selection set in the legacy
+// plain-line persistence format. Lines before the first header belong to the current
+// selection, so the oldest files (one relative path per line) are a subset of that format.
+const selectionSetHeaderPrefix = "# set: "
+
+// Output formats stored in the session state.
+const (
+	outputFormatPlain = "plain" // Plain text bundle only.
+	outputFormatHTML  = "html"  // Plain text plus a text/html rendering (-html).
+)
+
+// selectionState is the content of the persistence file: the current selection, which is
+// restored on start, any number of named selection sets, and the last session's UI state.
+type selectionState struct {
+	current []string            // Current selection (relative paths).
+	sets    map[string][]string // Named selection sets (key: set name, value: relative paths).
+
+	patterns    []string            // Glob patterns of the current selection, evaluated on load.
+	setPatterns map[string][]string // Glob patterns of named sets (key: set name).
+	session     sessionState        // UI state restored on start.
+	legacy      bool                // Flag set when the state was read from the plain-line format.
+
+	fingerprints map[string]fileFingerprint // Size and content hash of saved paths, used to find moved files.
+	gitHead      string                     // Commit checked out at the last save, the base for git rename detection.
+}
+
+// fileFingerprint identifies a file's content independently of its path.
+type fileFingerprint struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// sessionState is the UI state persisted alongside the selection.
+type sessionState struct {
+	FilterQuery  string `json:"filterQuery,omitempty"`  // Filter query if filter mode was active.
+	ShowHidden   bool   `json:"showHidden,omitempty"`   // Whether hidden paths were shown.
+	Cursor       string `json:"cursor,omitempty"`       // Relative path of the focused item.
+	OutputFormat string `json:"outputFormat,omitempty"` // outputFormatPlain or outputFormatHTML.
+	ActiveSet    string `json:"activeSet,omitempty"`    // Name of the loaded selection set.
+	ActivePreset string `json:"activePreset,omitempty"` // Name of the loaded team preset.
+	SortMode     string `json:"sortMode,omitempty"`     // Sort mode of the file list.
+	Details      bool   `json:"details,omitempty"`      // Whether rows showed the detail columns.
+}
+
+// stateFile is the on-disk JSON representation of selectionState.
+type stateFile struct {
+	Version   int                 `json:"version"`
+	Selection []string            `json:"selection"`
+	Sets      map[string][]string `json:"sets,omitempty"`
+	Session   sessionState        `json:"session"`
+
+	Patterns    []string            `json:"patterns,omitempty"`
+	SetPatterns map[string][]string `json:"setPatterns,omitempty"`
+
+	Fingerprints map[string]fileFingerprint `json:"fingerprints,omitempty"`
+	GitHead      string                     `json:"gitHead,omitempty"`
+}
+
+// newSelectionState returns an empty, ready-to-use state.
+func newSelectionState() selectionState {
+	return selectionState{
+		current:      []string{},
+		sets:         make(map[string][]string),
+		setPatterns:  make(map[string][]string),
+		fingerprints: make(map[string]fileFingerprint),
+	}
+}
+
+// parseSelectionState parses the persistence file. Content starting with a JSON object is the
+// structured format; anything else is the legacy format. A damaged JSON file (truncated, edited
+// by hand, without a version) is an error rather than legacy lines, since the next save would
+// otherwise replace its sets, patterns and session with whatever the lines yielded.
+func parseSelectionState(content string) (selectionState, error) {
+	if !startsJSONObject(content) {
+		return parseLegacySelectionState(content), nil
+	}
+	var file stateFile
+	if err := json.Unmarshal([]byte(content), &file); err != nil {
+		return newSelectionState(), fmt.Errorf("persistence file is not valid JSON: %w", err)
+	}
+	if file.Version <= 0 {
+		return newSelectionState(), errors.New("persistence file has no format version")
+	}
+	if file.Version > stateFormatVersion {
+		return newSelectionState(), fmt.Errorf("persistence file has format version %d, this build supports up to %d", file.Version, stateFormatVersion)
+	}
+	state := newSelectionState()
+	if file.Selection != nil {
+		state.current = file.Selection
+	}
+	for name, paths := range file.Sets {
+		if paths == nil {
+			paths = []string{}
+		}
+		state.sets[name] = paths
+	}
+	state.session = file.Session
+	state.patterns = file.Patterns
+	for name, patterns := range file.SetPatterns {
+		state.setPatterns[name] = patterns
+	}
+	for relativePath, fp := range file.Fingerprints {
+		state.fingerprints[relativePath] = fp
+	}
+	state.gitHead = file.GitHead
+	return state, nil
+}
+
+// startsJSONObject reports whether content begins like a JSON object: '{' followed by a key, the
+// closing brace or nothing (a truncated file). A legacy file may start with '{' too, when its
+// first path does (e.g., "{templates}/x.go").
+func startsJSONObject(content string) bool {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), "{")
+	if !ok {
+		return false
+	}
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == '"' || rest[0] == '}'
+}
+
+// parseLegacySelectionState parses the plain-line format: one relative path per line,
+// optionally followed by "# set: <name>" sections holding named sets.
+func parseLegacySelectionState(content string) selectionState {
+	state := newSelectionState()
+	state.legacy = true
+	section := "" // Empty while reading the current selection.
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(trimmed, strings.TrimSpace(selectionSetHeaderPrefix)); ok {
+			section = strings.TrimSpace(name)
+			if _, exists := state.sets[section]; !exists {
+				state.sets[section] = []string{}
+			}
+			continue
+		}
+		if section == "" {
+			state.current = append(state.current, trimmed)
+		} else {
+			state.sets[section] = append(state.sets[section], trimmed)
+		}
+	}
+	return state
+}
+
+// format serialises the state into the structured persistence format. Paths are sorted so
+// the file content is stable between saves.
+func (s selectionState) format() ([]byte, error) {
+	file := stateFile{
+		Version:   stateFormatVersion,
+		Selection: sortedCopy(s.current),
+		Sets:      make(map[string][]string, len(s.sets)),
+		Session:   s.session,
+
+		Patterns:     s.patterns, // Order matters for readability; kept as entered.
+		SetPatterns:  make(map[string][]string),
+		Fingerprints: make(map[string]fileFingerprint),
+		GitHead:      s.gitHead,
+	}
+	for name, paths := range s.sets {
+		file.Sets[name] = sortedCopy(paths)
+		if patterns := s.setPatterns[name]; len(patterns) > 0 {
+			file.SetPatterns[name] = patterns
+		}
+	}
+	// Only keep fingerprints of paths that are still referenced.
+	for _, relativePath := range s.referencedPaths() {
+		if fp, ok := s.fingerprints[relativePath]; ok {
+			file.Fingerprints[relativePath] = fp
+		}
+	}
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// isEmpty reports whether there is nothing worth persisting. Session state alone does not
+// keep the file alive.
+func (s selectionState) isEmpty() bool {
+	return len(s.current) == 0 && len(s.patterns) == 0 && len(s.sets) == 0
+}
+
+// referencedPaths returns every path of the current selection and all named sets, without
+// duplicates and in alphabetical order.
+func (s selectionState) referencedPaths() []string {
+	seen := make(map[string]struct{})
+	for _, relativePath := range s.current {
+		seen[relativePath] = struct{}{}
+	}
+	for _, paths := range s.sets {
+		for _, relativePath := range paths {
+			seen[relativePath] = struct{}{}
+		}
+	}
+	paths := make([]string, 0, len(seen))
+	for relativePath := range seen {
+		paths = append(paths, relativePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// setNames returns the names of all named sets in alphabetical order.
+func (s selectionState) setNames() []string {
+	names := make([]string, 0, len(s.sets))
+	for name := range s.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateSetName checks that name is usable as a set name.
+func validateSetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("set name must not be empty")
+	}
+	if strings.ContainsAny(name, "\r\n") {
+		return errors.New("set name must be a single line")
+	}
+	return nil
+}
+
+// sortedCopy returns a sorted copy of paths, leaving the input untouched. The result is
+// never nil, so it is always encoded as a JSON array.
+func sortedCopy(paths []string) []string {
+	sorted := slices.Clone(paths)
+	if sorted == nil {
+		sorted = []string{}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// readSelectionState loads the persistence file of targetDir. A missing file yields an empty state.
+func readSelectionState(targetDir string) (selectionState, error) {
+	filePath := getPersistenceFilePath(targetDir)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return newSelectionState(), nil
+		}
+		return newSelectionState(), fmt.Errorf("reading persistence file '%s': %w", filePath, err)
+	}
+	state, err := parseSelectionState(string(content))
+	if err != nil {
+		return state, fmt.Errorf("reading persistence file '%s': %w", filePath, err)
+	}
+	return state, nil
+}
+
+// writeSelectionState writes the state to the persistence file of targetDir.
+// If the state is empty, the file is removed instead.
+func writeSelectionState(targetDir string, state selectionState) error {
+	filePath := getPersistenceFilePath(targetDir)
+
+	// If there is nothing to persist, remove the persistence file to clean up.
+	if state.isEmpty() {
+		err := os.Remove(filePath)
+		// Report error only if it's *not* "file doesn't exist" (which is fine).
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed remove persistence file '%s': %w", filePath, err)
+		}
+		return nil
+	}
+
+	if stateMode == stateModeTree {
+		// Keep the in-tree file out of `git status`; failing to do so must not block saving.
+		_ = ensureGitExcluded(targetDir)
+	} else if err := os.MkdirAll(filepath.Dir(filePath), 0750); err != nil {
+		return fmt.Errorf("failed create state directory: %w", err)
+	}
+
+	content, err := state.format()
+	if err != nil {
+		return fmt.Errorf("failed encode persistence file: %w", err)
+	}
+	// Write file with rw-r----- permissions, atomically so a crash cannot truncate it.
+	if err := writeFileAtomic(filePath, content, 0640); err != nil {
+		return fmt.Errorf("failed write persistence file '%s': %w", filePath, err)
+	}
+	return nil
+}
+
+// updateSelectionState performs a read-modify-write of the persistence file, so changes
+// made by other operations (e.g., named sets) are preserved. The advisory lock serialises
+// concurrent yank instances working on the same directory.
+func updateSelectionState(targetDir string, modify func(state *selectionState) error) (selectionState, error) {
+	lock, err := lockStateFile(getPersistenceFilePath(targetDir))
+	if err != nil {
+		return newSelectionState(), err
+	}
+	defer lock.unlock()
+
+	state, err := readSelectionState(targetDir)
+	if err != nil {
+		return state, err
+	}
+	if err := modify(&state); err != nil {
+		return state, err
+	}
+	return state, writeSelectionState(targetDir, state)
+}
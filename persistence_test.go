@@ -0,0 +1,140 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSelectionState(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantErr    string   // Substring of the expected error; "" if none.
+		wantLegacy bool     // Whether the legacy format was detected.
+		current    []string // Expected current selection.
+		sets       map[string][]string
+	}{
+		{
+			name:       "empty file",
+			content:    "",
+			wantLegacy: true,
+			current:    []string{},
+		},
+		{
+			name:       "legacy lines",
+			content:    "main.go\n\n  internal/a.go  \n",
+			wantLegacy: true,
+			current:    []string{"main.go", "internal/a.go"},
+		},
+		{
+			name:       "legacy sets",
+			content:    "main.go\n# set: api\napi/a.go\napi/b.go\n# set: empty\n",
+			wantLegacy: true,
+			current:    []string{"main.go"},
+			sets:       map[string][]string{"api": {"api/a.go", "api/b.go"}, "empty": {}},
+		},
+		{
+			name:       "legacy path starting with a brace",
+			content:    "{templates}/x.go\nmain.go\n",
+			wantLegacy: true,
+			current:    []string{"{templates}/x.go", "main.go"},
+		},
+		{
+			name:    "json",
+			content: `{"version": 1, "selection": ["b.go", "a.go"], "sets": {"api": ["api/a.go"], "none": null}}`,
+			current: []string{"b.go", "a.go"},
+			sets:    map[string][]string{"api": {"api/a.go"}, "none": {}},
+		},
+		{
+			name:    "json without selection",
+			content: "{\n  \"version\": 1\n}\n",
+			current: []string{},
+		},
+		{
+			name:    "truncated json",
+			content: "{\n  \"version\": 1,\n  \"selection\": [\"a.go\"",
+			wantErr: "not valid JSON",
+		},
+		{
+			name:    "only an opening brace",
+			content: "{",
+			wantErr: "not valid JSON",
+		},
+		{
+			name:    "json without version",
+			content: `{"selection": ["a.go"]}`,
+			wantErr: "no format version",
+		},
+		{
+			name:    "json with version 0",
+			content: `{"version": 0, "selection": ["a.go"]}`,
+			wantErr: "no format version",
+		},
+		{
+			name:    "json from a newer build",
+			content: `{"version": 99}`,
+			wantErr: "format version 99",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := parseSelectionState(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if state.legacy != tt.wantLegacy {
+				t.Errorf("legacy = %v, want %v", state.legacy, tt.wantLegacy)
+			}
+			if !slices.Equal(state.current, tt.current) {
+				t.Errorf("current = %q, want %q", state.current, tt.current)
+			}
+			if len(state.sets) != len(tt.sets) {
+				t.Errorf("sets = %q, want %q", state.sets, tt.sets)
+			}
+			for name, paths := range tt.sets {
+				if got, ok := state.sets[name]; !ok || !slices.Equal(got, paths) {
+					t.Errorf("set %q = %q, want %q", name, got, paths)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectionStateRoundTrip(t *testing.T) {
+	state := newSelectionState()
+	state.current = []string{"b.go", "a.go"}
+	state.sets["api"] = []string{"api/a.go"}
+	state.patterns = []string{"internal/**", "!**/*_test.go"}
+	state.session = sessionState{FilterQuery: "ext:go", SortMode: sortModeSize, Details: true}
+
+	content, err := state.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseSelectionState(string(content))
+	if err != nil {
+		t.Fatalf("parsing the formatted state: %v", err)
+	}
+	if parsed.legacy {
+		t.Error("formatted state was parsed as legacy lines")
+	}
+	if want := []string{"a.go", "b.go"}; !slices.Equal(parsed.current, want) {
+		t.Errorf("current = %q, want %q", parsed.current, want)
+	}
+	if !slices.Equal(parsed.sets["api"], state.sets["api"]) {
+		t.Errorf("set api = %q, want %q", parsed.sets["api"], state.sets["api"])
+	}
+	if !slices.Equal(parsed.patterns, state.patterns) {
+		t.Errorf("patterns = %q, want %q", parsed.patterns, state.patterns)
+	}
+	if parsed.session != state.session {
+		t.Errorf("session = %+v, want %+v", parsed.session, state.session)
+	}
+}
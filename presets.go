@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Team Presets ---
+
+// presetsFileName is the committed, team-shared presets file. Unlike the personal
+// persistenceDotFileName state, it is meant to be checked into the repository.
+const presetsFileName = ".yank.toml"
+
+// preset is a named, shareable selection defined in presetsFileName:
+//
+//	[[preset]]
+//	name = "core domain model"
+//	description = "Entities, value objects and their repositories"
+//	patterns = ["internal/domain/**", "!**/*_test.go"]
+//	files = ["docs/domain.md"]
+//	preamble = "You are reviewing the core domain model of our billing service."
+type preset struct {
+	Name        string   `toml:"name"`        // Unique name, used by the picker and -preset.
+	Description string   `toml:"description"` // One-line explanation shown in the picker.
+	Patterns    []string `toml:"patterns"`    // Glob patterns, relative to the presets file.
+	Files       []string `toml:"files"`       // Explicit files, relative to the presets file.
+	Preamble    string   `toml:"preamble"`    // Optional text placed before the files in the bundle.
+}
+
+// presetCatalog holds the presets that apply to the scanned directory.
+type presetCatalog struct {
+	path    string   // Path of the presets file ("" if none was found).
+	prefix  string   // Scanned directory relative to the presets file's directory, with '/' ("" if the same).
+	presets []preset // Presets in file order.
+}
+
+// findPresetsFile looks for presetsFileName in targetDir and its parents up to the root of
+// the git work tree, so presets committed at the repository root apply to subdirectories too.
+// Outside git only targetDir is searched. It returns "" if there is no presets file.
+func findPresetsFile(targetDir string) string {
+	candidate := filepath.Join(targetDir, presetsFileName)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	toplevel, err := gitOutput(targetDir, "rev-parse", "--show-toplevel")
+	if err != nil || toplevel == "" {
+		return ""
+	}
+	prefix, err := gitOutput(targetDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return ""
+	}
+	// Walk up from targetDir using the git prefix, which is immune to symlinked paths.
+	for dir := strings.TrimSuffix(prefix, "/"); dir != ""; {
+		dir = filepath.ToSlash(filepath.Dir(dir))
+		if dir == "." {
+			dir = ""
+		}
+		candidate := filepath.Join(toplevel, filepath.FromSlash(dir), presetsFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// readPresetsFile parses and validates a presets file.
+func readPresetsFile(path string) ([]preset, error) {
+	var file struct {
+		Preset []preset `toml:"preset"`
+	}
+	meta, err := toml.DecodeFile(path, &file)
+	if err != nil {
+		return nil, fmt.Errorf("reading presets '%s': %w", path, err)
+	}
+	// Unknown keys are most likely typos ("pattern" instead of "patterns"), so report them.
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("presets '%s': unknown key '%s'", path, undecoded[0])
+	}
+	seen := make(map[string]struct{}, len(file.Preset))
+	for i, p := range file.Preset {
+		if strings.TrimSpace(p.Name) == "" {
+			return nil, fmt.Errorf("presets '%s': preset %d has no name", path, i+1)
+		}
+		if _, dup := seen[p.Name]; dup {
+			return nil, fmt.Errorf("presets '%s': duplicate preset '%s'", path, p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		for _, pattern := range p.Patterns {
+			if err := validatePattern(pattern); err != nil {
+				return nil, fmt.Errorf("presets '%s': preset '%s': %w", path, p.Name, err)
+			}
+		}
+	}
+	return file.Preset, nil
+}
+
+// loadPresets returns the presets applying to targetDir. A missing file yields an empty catalog.
+func loadPresets(targetDir string) (presetCatalog, error) {
+	path := findPresetsFile(targetDir)
+	if path == "" {
+		return presetCatalog{}, nil
+	}
+	presets, err := readPresetsFile(path)
+	if err != nil {
+		return presetCatalog{}, err
+	}
+	catalog := presetCatalog{path: path, presets: presets}
+	if rel, err := filepath.Rel(filepath.Dir(path), targetDir); err == nil && rel != "." {
+		catalog.prefix = filepath.ToSlash(rel)
+	}
+	return catalog, nil
+}
+
+// find returns the preset with the given name.
+func (c presetCatalog) find(name string) (preset, bool) {
+	for _, p := range c.presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return preset{}, false
+}
+
+// names returns the preset names in file order.
+func (c presetCatalog) names() []string {
+	names := make([]string, 0, len(c.presets))
+	for _, p := range c.presets {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// rebase converts the files and patterns of p, which are relative to the presets file, to
+// paths relative to the scanned directory. Entries that cannot match inside the scanned
+// directory are returned as dropped.
+func (c presetCatalog) rebase(p preset) (files, patterns, dropped []string) {
+	for _, file := range p.Files {
+		if rel, ok := c.rebasePath(filepath.ToSlash(file)); ok {
+			files = append(files, filepath.FromSlash(rel))
+		} else {
+			dropped = append(dropped, file)
+		}
+	}
+	for _, pattern := range p.Patterns {
+		glob, exclude := parsePattern(pattern)
+		rel, ok := c.rebasePattern(glob)
+		if !ok {
+			dropped = append(dropped, pattern)
+			continue
+		}
+		if exclude {
+			rel = patternExcludePrefix + rel
+		}
+		patterns = append(patterns, rel)
+	}
+	return files, patterns, dropped
+}
+
+// rebasePath strips the catalog prefix from a slash-separated path.
+func (c presetCatalog) rebasePath(slashPath string) (string, bool) {
+	if c.prefix == "" {
+		return slashPath, true
+	}
+	return strings.CutPrefix(slashPath, c.prefix+"/")
+}
+
+// rebasePattern matches the leading segments of a glob against the catalog prefix, one
+// segment at a time, and returns the rest of the glob. A "**" segment can cover the
+// remaining prefix, so it is kept together with everything after it: with the prefix
+// "internal/domain", "internal/**/*.go" becomes "**/*.go".
+func (c presetCatalog) rebasePattern(glob string) (string, bool) {
+	if c.prefix == "" {
+		return glob, true
+	}
+	segments := strings.Split(glob, "/")
+	dirs := strings.Split(c.prefix, "/")
+	for i, dir := range dirs {
+		if segments[i] == "**" {
+			return strings.Join(segments[i:], "/"), true
+		}
+		if i == len(segments)-1 {
+			return "", false // The glob ends at or above the scanned directory.
+		}
+		if ok, _ := doublestar.Match(segments[i], dir); !ok {
+			return "", false
+		}
+	}
+	return strings.Join(segments[len(dirs):], "/"), true
+}
+
+// loadPreset replaces the selection and patterns with those of the named preset and reports
+// the result in the status line. The preset's preamble is used for copies until another
+// preset or set is loaded.
+func (m *model) loadPreset(name string) (tea.Cmd, error) {
+	p, ok := m.presets.find(name)
+	if !ok {
+		return nil, fmt.Errorf("preset '%s' not found", name)
+	}
+	files, patterns, dropped := m.presets.rebase(p)
+	existing := m.existingPaths(files)
+
+	before := m.snapshotSelection()
+	clear(m.selected)
+	for _, relativePath := range existing {
+		m.selected[relativePath] = true
+	}
+	m.patterns = patterns
+	m.activePreset = name
+	m.activeSet = ""
+	m.recomputePatternMatches()
+	m.recordUndo(fmt.Sprintf("load preset '%s'", name), before)
+
+	status := fmt.Sprintf("Loaded preset '%s' (%d files, %d matched by patterns)", name, len(existing), len(m.patternMatched))
+	if missing := len(files) - len(existing); missing > 0 {
+		status += fmt.Sprintf(", %d missing", missing)
+	}
+	// Entries relative to the presets file's directory cannot apply in a subdirectory.
+	if len(dropped) > 0 {
+		status += fmt.Sprintf(", %d outside this directory skipped: %s", len(dropped), strings.Join(dropped, ", "))
+	}
+	return m.setStatus(status), nil
+}
+
+// activePreamble returns the preamble of the loaded preset, or "".
+func (m *model) activePreamble() string {
+	if m.activePreset == "" {
+		return ""
+	}
+	p, _ := m.presets.find(m.activePreset)
+	return p.Preamble
+}
+
+// --- Preset Picker ---
+
+// presetPicker holds the state of the read-only overlay listing the team presets.
+type presetPicker struct {
+	active bool // Flag indicating whether the picker replaces the file list.
+	cursor int  // Index of the focused preset.
+}
+
+// openPresetPicker shows the picker, focusing the active preset if there is one.
+func (m *model) openPresetPicker() {
+	m.presetPicker = presetPicker{active: true}
+	for i, name := range m.presets.names() {
+		if name == m.activePreset {
+			m.presetPicker.cursor = i
+		}
+	}
+}
+
+// updatePresetPicker handles key presses while the preset picker is open.
+func (m model) updatePresetPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.PresetClose):
+		m.presetPicker.active = false
+
+	case key.Matches(msg, m.list.KeyMap.CursorUp):
+		if m.presetPicker.cursor > 0 {
+			m.presetPicker.cursor--
+		}
+
+	case key.Matches(msg, m.list.KeyMap.CursorDown):
+		if m.presetPicker.cursor < len(m.presets.presets)-1 {
+			m.presetPicker.cursor++
+		}
+
+	case key.Matches(msg, m.keys.PresetLoad):
+		if m.presetPicker.cursor < len(m.presets.presets) {
+			cmd, err := m.loadPreset(m.presets.presets[m.presetPicker.cursor].Name)
+			if err != nil {
+				m.lastErr = err
+				return m, nil
+			}
+			m.presetPicker.active = false
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+// viewPresetPicker renders the preset picker in place of the file list.
+func (m model) viewPresetPicker() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Team presets:") + "\n")
+	if m.presets.path != "" {
+		sb.WriteString(helpStyle.Render(m.presets.path) + "\n")
+	}
+	sb.WriteString("\n")
+
+	if len(m.presets.presets) == 0 {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("No presets. Define them in a committed %s file (see the README).", presetsFileName)) + "\n")
+	}
+	for i, p := range m.presets.presets {
+		marker := "  "
+		if p.Name == m.activePreset {
+			marker = checkedStyle.Render("* ")
+		}
+		line := fmt.Sprintf("%s (%d patterns, %d files)", p.Name, len(p.Patterns), len(p.Files))
+		if p.Preamble != "" {
+			line += " +preamble"
+		}
+		if i == m.presetPicker.cursor {
+			sb.WriteString(marker + selectedStyle.Render("> "+line) + "\n")
+		} else {
+			sb.WriteString(marker + itemStyle.Render("  "+line) + "\n")
+		}
+		if p.Description != "" {
+			sb.WriteString(helpStyle.Render("      "+p.Description) + "\n")
+		}
+	}
+	sb.WriteString("\n" + helpStyle.Render(keyHints(m.keys.PresetLoad, m.keys.PresetClose)))
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	}
+	return docStyle.Render(sb.String())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPresetCatalogRebase(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		preset       preset
+		wantFiles    []string
+		wantPatterns []string
+		wantDropped  []string
+	}{
+		{
+			name:         "same directory",
+			preset:       preset{Files: []string{"main.go"}, Patterns: []string{"internal/**", "!**/*_test.go"}},
+			wantFiles:    []string{"main.go"},
+			wantPatterns: []string{"internal/**", "!**/*_test.go"},
+		},
+		{
+			name:        "files below and outside the prefix",
+			prefix:      "internal/domain",
+			preset:      preset{Files: []string{"internal/domain/user.go", "main.go", "internal/domain.go"}},
+			wantFiles:   []string{"user.go"},
+			wantDropped: []string{"main.go", "internal/domain.go"},
+		},
+		{
+			name:         "literal prefix of the pattern",
+			prefix:       "internal/domain",
+			preset:       preset{Patterns: []string{"internal/domain/*.go", "internal/domain/model/"}},
+			wantPatterns: []string{"*.go", "model/**"},
+		},
+		{
+			name:         "double star covers the rest of the prefix",
+			prefix:       "internal/domain",
+			preset:       preset{Patterns: []string{"internal/**", "internal/**/*.go", "**/*.md", "!internal/**/*_test.go"}},
+			wantPatterns: []string{"**", "**/*.go", "**/*.md", "!**/*_test.go"},
+		},
+		{
+			name:         "trailing slash on a parent directory",
+			prefix:       "internal/domain",
+			preset:       preset{Patterns: []string{"internal/"}},
+			wantPatterns: []string{"**"},
+		},
+		{
+			name:         "wildcard segment",
+			prefix:       "internal/domain",
+			preset:       preset{Patterns: []string{"internal/*/*.go", "intern?l/dom*/x.go"}},
+			wantPatterns: []string{"*.go", "x.go"},
+		},
+		{
+			name:        "outside or above the prefix",
+			prefix:      "internal/domain",
+			preset:      preset{Patterns: []string{"cmd/**", "internal/auth/*.go", "internal/*.go", "internal/domain"}},
+			wantDropped: []string{"cmd/**", "internal/auth/*.go", "internal/*.go", "internal/domain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := presetCatalog{prefix: tt.prefix}
+			files, patterns, dropped := c.rebase(tt.preset)
+
+			var wantFiles []string
+			for _, f := range tt.wantFiles {
+				wantFiles = append(wantFiles, filepath.FromSlash(f))
+			}
+			if !slices.Equal(files, wantFiles) {
+				t.Errorf("files = %q, want %q", files, wantFiles)
+			}
+			if !slices.Equal(patterns, tt.wantPatterns) {
+				t.Errorf("patterns = %q, want %q", patterns, tt.wantPatterns)
+			}
+			if !slices.Equal(dropped, tt.wantDropped) {
+				t.Errorf("dropped = %q, want %q", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
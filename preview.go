@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- File Preview ---
+
+const (
+	previewMaxBytes = 256 << 10 // Only the beginning of larger files is previewed.
+	previewMinWidth = 60        // Below this terminal width the preview is not shown.
+	previewTabWidth = 4         // Tabs are expanded, since terminals render them inconsistently.
+)
+
+var (
+	// previewBorderStyle separates the preview from the file list (set by applyTheme).
+	previewBorderStyle lipgloss.Style
+	// previewStyleName is the chroma style of the preview, chosen by the theme. Empty means no
+	// highlighting (NO_COLOR).
+	previewStyleName string
+)
+
+// previewPane holds the state of the preview shown right of the file list.
+type previewPane struct {
+	visible    bool          // Flag indicating whether the preview is shown (toggled by the user).
+	offsetPath string        // File the scroll offset belongs to; focusing another file resets it.
+	offset     int           // Index of the first preview line shown.
+	cache      *previewCache // Highlighted content of the last previewed file; shared by model copies.
+}
+
+// previewContent is the highlighted content of one file.
+type previewContent struct {
+	path  string   // Relative path of the file.
+	lines []string // Highlighted lines (ANSI escape sequences, no trailing newline).
+	note  string   // Explanation shown instead of or after the content (binary, truncated, error).
+}
+
+// previewLoadedMsg carries a file read and highlighted in the background.
+type previewLoadedMsg struct{ content previewContent }
+
+// previewCache holds the content of the last previewed file. Files are read and highlighted by
+// a goroutine of its own, so moving the cursor over large files never stalls rendering; only
+// the latest request is kept when the cursor moves faster than files are loaded. The cache is
+// only used from the Bubble Tea goroutine.
+type previewCache struct {
+	current  previewContent      // Last loaded file.
+	pending  string              // File requested but not loaded yet ("" if none).
+	requests chan string         // Files to load, read by the loading goroutine (holds only the latest).
+	results  chan previewContent // Loaded files, received by waitForPreview.
+}
+
+// newPreviewPane creates a hidden preview with an empty cache and starts the goroutine loading
+// the files below targetDir.
+func newPreviewPane(targetDir string) previewPane {
+	c := &previewCache{requests: make(chan string, 1), results: make(chan previewContent)}
+	go func() {
+		for relativePath := range c.requests {
+			c.results <- loadPreview(targetDir, relativePath)
+		}
+	}()
+	return previewPane{cache: c}
+}
+
+// get returns the content of relativePath if it is loaded. Otherwise the file is requested,
+// replacing an older request still waiting, and the result arrives as a previewLoadedMsg.
+func (c *previewCache) get(relativePath string) (previewContent, bool) {
+	if c.current.path == relativePath {
+		return c.current, true
+	}
+	if c.pending != relativePath {
+		select {
+		case <-c.requests: // Drop the request of a file the cursor has already left.
+		default:
+		}
+		c.requests <- relativePath
+		c.pending = relativePath
+	}
+	return previewContent{}, false
+}
+
+// store keeps a loaded file. Files are loaded again once another file was focused, so a
+// revisited file shows its current content.
+func (c *previewCache) store(content previewContent) {
+	c.current = content
+	if c.pending == content.path {
+		c.pending = ""
+	}
+}
+
+// waitForPreview returns the command receiving the next loaded file.
+func waitForPreview(results <-chan previewContent) tea.Cmd {
+	return func() tea.Msg {
+		return previewLoadedMsg{content: <-results}
+	}
+}
+
+// loadPreview reads the beginning of relativePath and highlights it.
+func loadPreview(targetDir, relativePath string) previewContent {
+	c := previewContent{path: relativePath}
+	file, err := os.Open(filepath.Join(targetDir, relativePath))
+	if err != nil {
+		c.note = fmt.Sprintf("cannot read file: %v", err)
+		return c
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		c.note = fmt.Sprintf("cannot read file: %v", err)
+		return c
+	}
+	content := make([]byte, previewMaxBytes)
+	n, err := io.ReadFull(file, content)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.note = fmt.Sprintf("cannot read file: %v", err)
+		return c
+	}
+	content = content[:n]
+
+	// A NUL byte near the start is the usual sign of a binary file.
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		c.note = fmt.Sprintf("binary file (%s)", formatByteSize(int(info.Size())))
+		return c
+	}
+	if info.Size() > int64(n) {
+		c.note = fmt.Sprintf("showing the first %s of %s", formatByteSize(n), formatByteSize(int(info.Size())))
+	}
+	c.lines = highlightLines(relativePath, string(content))
+	return c
+}
+
+// highlightLines highlights content for the terminal and splits it into lines. Each line is
+// formatted on its own so that colours never continue into the next line.
+func highlightLines(relativePath, content string) []string {
+	content = strings.ReplaceAll(content, "\t", strings.Repeat(" ", previewTabWidth))
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if previewStyleName == "" {
+		return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	lexer := lexers.Match(relativePath)
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	formatter := formatters.Get("terminal256")
+	style := styles.Get(previewStyleName)
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, content)
+	if err != nil {
+		return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	var lines []string
+	for _, tokens := range chroma.SplitTokensIntoLines(iterator.Tokens()) {
+		var sb strings.Builder
+		if err := formatter.Format(&sb, style, chroma.Literator(tokens...)); err != nil {
+			return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		}
+		lines = append(lines, strings.TrimRight(sb.String(), "\n"))
+	}
+	return lines
+}
+
+// previewWidths splits the available width between the file list and the preview. The preview
+// gets no width if it is hidden or the terminal is too narrow.
+func (m *model) previewWidths(width int) (listWidth, previewWidth int) {
+	if !m.preview.visible || width < previewMinWidth {
+		return width, 0
+	}
+	listWidth = width * 2 / 5
+	return listWidth, width - listWidth
+}
+
+// resize distributes the terminal size between the list and the preview.
+func (m *model) resize() {
+	h, v := docStyle.GetFrameSize()
+	listWidth, _ := m.previewWidths(m.width - h)
+	m.list.SetSize(listWidth, m.height-v)
+}
+
+// focusedPath returns the relative path of the focused list item, or "".
+func (m *model) focusedPath() string {
+	if currentItem, ok := m.list.SelectedItem().(item); ok {
+		return currentItem.name
+	}
+	return ""
+}
+
+// scrollPreview moves the preview of the focused file by delta lines.
+func (m *model) scrollPreview(delta int) {
+	relativePath := m.focusedPath()
+	if relativePath == "" {
+		return
+	}
+	if m.preview.offsetPath != relativePath {
+		m.preview.offsetPath = relativePath
+		m.preview.offset = 0
+	}
+	content, _ := m.preview.cache.get(relativePath)
+	lines := len(content.lines)
+	m.preview.offset = max(0, min(m.preview.offset+delta, lines-m.previewBodyHeight()))
+}
+
+// previewBodyHeight is the number of content lines the preview shows below its header.
+func (m *model) previewBodyHeight() int {
+	return max(m.list.Height()-2, 1)
+}
+
+// viewPreview renders the preview of the focused file with the given outer width.
+func (m model) viewPreview(width int) string {
+	innerWidth := width - previewBorderStyle.GetHorizontalFrameSize()
+	clip := lipgloss.NewStyle().MaxWidth(innerWidth)
+	height := m.previewBodyHeight()
+
+	relativePath := m.focusedPath()
+	var sb strings.Builder
+	if relativePath == "" {
+		sb.WriteString(helpStyle.Render("Nothing to preview"))
+		return previewBorderStyle.Height(m.list.Height()).Render(sb.String())
+	}
+
+	cache, loaded := m.preview.cache.get(relativePath)
+	if !loaded {
+		sb.WriteString(clip.Render(titleStyle.Render(relativePath)) + "\n")
+		sb.WriteString(helpStyle.Render("Loading…"))
+		return previewBorderStyle.Height(m.list.Height()).Render(sb.String())
+	}
+	offset := 0
+	if m.preview.offsetPath == relativePath {
+		offset = min(m.preview.offset, max(len(cache.lines)-height, 0))
+	}
+	end := min(offset+height, len(cache.lines))
+
+	header := relativePath
+	if len(cache.lines) > 0 {
+		header += fmt.Sprintf("  %d-%d/%d", offset+1, end, len(cache.lines))
+	}
+	sb.WriteString(clip.Render(titleStyle.Render(header)) + "\n")
+	if cache.note != "" {
+		sb.WriteString(clip.Render(helpStyle.Render(cache.note)) + "\n")
+		end = min(end, offset+height-1)
+	}
+	for _, line := range cache.lines[offset:end] {
+		sb.WriteString(clip.Render(line) + "\n")
+	}
+	return previewBorderStyle.Height(m.list.Height()).Render(strings.TrimSuffix(sb.String(), "\n"))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Filter Query Language ---
+
+// A filter query is a list of space-separated terms that must all match a path:
+//
+//	internal go              fuzzy terms (the default; see fuzzyMatch)
+//	ext:go  ext:ts,tsx       file extension, with alternatives
+//	dir:internal/            a directory anywhere in the path
+//	'exact  ^prefix  suffix$ substring, path prefix and path suffix
+//	re:/_v[0-9]+\.go$/       regular expression (Go syntax, case-insensitive)
+//	!_test  !ext:md          negation of any term (a bare negated term is a substring)
+//
+// Terms compare case-insensitively, like the fuzzy matching, and see '/' as the separator on
+// every platform.
+
+// filterTermKind tells how a term is compared with a path.
+type filterTermKind int
+
+const (
+	termFuzzy     filterTermKind = iota // Characters in order, gaps allowed.
+	termExact                           // Substring.
+	termPrefix                          // Start of the path.
+	termSuffix                          // End of the path.
+	termWhole                           // The whole path (^term$).
+	termExtension                       // File extension, one of several.
+	termDirectory                       // Directory segments anywhere in the path.
+	termRegexp                          // Regular expression.
+)
+
+// filterTerm is one term of a parsed filter query.
+type filterTerm struct {
+	kind   filterTermKind
+	value  string         // Lower-cased text of the term, without operators.
+	runes  []rune         // Runes of value, for termFuzzy.
+	values []string       // Extensions of a termExtension, with their dot.
+	re     *regexp.Regexp // Compiled termRegexp.
+	negate bool           // Flag set for terms prefixed with '!'.
+}
+
+// filterQuery is a parsed filter query; a path matches if every term does.
+type filterQuery []filterTerm
+
+// parseFilterQuery splits a query into its terms. Incomplete terms, as they occur while typing
+// ("ext:", "!"), are skipped instead of matching nothing; an invalid regular expression is an
+// error.
+func parseFilterQuery(query string) (filterQuery, error) {
+	var terms filterQuery
+	for _, field := range strings.Fields(query) {
+		var term filterTerm
+		if rest, ok := strings.CutPrefix(field, "!"); ok {
+			term.negate = true
+			field = rest
+		}
+		switch {
+		case strings.HasPrefix(field, "re:"):
+			pattern := strings.TrimPrefix(field, "re:")
+			// The slashes are optional; the closing one is missing while the pattern is typed.
+			if rest, ok := strings.CutPrefix(pattern, "/"); ok {
+				pattern = strings.TrimSuffix(rest, "/")
+			}
+			if pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("regular expression: %w", err)
+			}
+			term.kind, term.value, term.re = termRegexp, pattern, re
+		case strings.HasPrefix(field, "ext:"):
+			for _, ext := range strings.Split(lowerRunes(strings.TrimPrefix(field, "ext:")), ",") {
+				if ext = strings.TrimPrefix(ext, "."); ext != "" {
+					term.values = append(term.values, "."+ext)
+				}
+			}
+			if len(term.values) == 0 {
+				continue
+			}
+			term.kind = termExtension
+		case strings.HasPrefix(field, "dir:"):
+			term.kind, term.value = termDirectory, strings.Trim(lowerRunes(strings.TrimPrefix(field, "dir:")), "/")
+		case strings.HasPrefix(field, "'"):
+			term.kind, term.value = termExact, lowerRunes(field[1:])
+		case strings.HasPrefix(field, "^") && strings.HasSuffix(field, "$") && len(field) > 1:
+			term.kind, term.value = termWhole, lowerRunes(field[1:len(field)-1])
+		case strings.HasPrefix(field, "^"):
+			term.kind, term.value = termPrefix, lowerRunes(field[1:])
+		case strings.HasSuffix(field, "$"):
+			term.kind, term.value = termSuffix, lowerRunes(field[:len(field)-1])
+		case term.negate:
+			// A negated fuzzy term would exclude far too much, so "!_test" means "no _test in the path".
+			term.kind, term.value = termExact, lowerRunes(field)
+		default:
+			term.kind, term.value = termFuzzy, lowerRunes(field)
+			term.runes = []rune(term.value)
+		}
+		if term.value == "" && term.kind != termExtension {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// lowerRunes lower-cases s rune by rune, so rune positions in the result are those of s.
+func lowerRunes(s string) string {
+	return strings.Map(unicode.ToLower, s)
+}
+
+// match reports whether relativePath matches every term. The score sums the scores of the
+// fuzzy terms (higher is better); the other terms either match or not.
+func (q filterQuery) match(relativePath string) (score int, ok bool) {
+	slashPath := filepath.ToSlash(relativePath)
+	lowerPath := lowerRunes(slashPath)
+	for _, term := range q {
+		termScore, positions := term.match(slashPath, lowerPath)
+		if (positions != nil) == term.negate {
+			return 0, false
+		}
+		if !term.negate {
+			score += termScore
+		}
+	}
+	return score, true
+}
+
+// highlights flags the runes of relativePath matched by the terms of the query; negated terms
+// match nothing to show.
+func (q filterQuery) highlights(relativePath string) []bool {
+	slashPath := filepath.ToSlash(relativePath)
+	lowerPath := lowerRunes(slashPath)
+	matched := make([]bool, utf8.RuneCountInString(relativePath))
+	for _, term := range q {
+		if term.negate {
+			continue
+		}
+		_, positions := term.match(slashPath, lowerPath)
+		for _, position := range positions {
+			matched[position] = true
+		}
+	}
+	return matched
+}
+
+// match compares a single term, ignoring its negation. It returns the rune positions of the
+// matched characters, nil if the term does not match; only fuzzy terms are scored.
+func (t filterTerm) match(slashPath, lowerPath string) (score int, positions []int) {
+	// span returns the positions of the text at byte offset start of lowerPath (or of slashPath,
+	// whose rune positions are the same).
+	span := func(text string, start, end int) []int {
+		if start < 0 {
+			return nil
+		}
+		first := utf8.RuneCountInString(text[:start])
+		positions := make([]int, 0, end-start)
+		for position := first; position < first+utf8.RuneCountInString(text[start:end]); position++ {
+			positions = append(positions, position)
+		}
+		return positions
+	}
+	switch t.kind {
+	case termFuzzy:
+		score, positions, ok := fuzzyMatch(t.runes, slashPath)
+		if !ok {
+			return 0, nil
+		}
+		return score, positions
+	case termExact:
+		start := strings.Index(lowerPath, t.value)
+		return 0, span(lowerPath, start, start+len(t.value))
+	case termPrefix:
+		if !strings.HasPrefix(lowerPath, t.value) {
+			return 0, nil
+		}
+		return 0, span(lowerPath, 0, len(t.value))
+	case termSuffix:
+		if !strings.HasSuffix(lowerPath, t.value) {
+			return 0, nil
+		}
+		return 0, span(lowerPath, len(lowerPath)-len(t.value), len(lowerPath))
+	case termWhole:
+		if lowerPath != t.value {
+			return 0, nil
+		}
+		return 0, span(lowerPath, 0, len(lowerPath))
+	case termExtension:
+		ext := path.Ext(lowerPath)
+		for _, value := range t.values {
+			if ext == value {
+				return 0, span(lowerPath, len(lowerPath)-len(ext), len(lowerPath))
+			}
+		}
+		return 0, nil
+	case termDirectory:
+		// Compare whole segments, so "dir:int" does not match "internal/". The leading slash
+		// added for the comparison makes the index point at the segment itself.
+		start := strings.Index("/"+path.Dir(lowerPath)+"/", "/"+t.value+"/")
+		return 0, span(lowerPath, start, start+len(t.value))
+	case termRegexp:
+		if loc := t.re.FindStringIndex(slashPath); loc != nil {
+			return 0, span(slashPath, loc[0], loc[1])
+		}
+		return 0, nil
+	}
+	return 0, nil
+}
+
+// filterMatch is a path matching a filter query, with its fuzzy score.
+type filterMatch struct {
+	path  string
+	score int
+}
+
+// matchFiles returns the paths matching the query, in their original order.
+func (q filterQuery) matchFiles(paths []string) []filterMatch {
+	var matches []filterMatch
+	for _, relativePath := range paths {
+		if score, ok := q.match(relativePath); ok {
+			matches = append(matches, filterMatch{path: relativePath, score: score})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFilterQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string // Substring of the expected error; "" if none.
+		kinds   []filterTermKind
+		values  []string // Expected value of each term (its extensions, comma-joined, for ext:).
+		negate  []bool
+	}{
+		{query: "", kinds: nil},
+		{query: "   ", kinds: nil},
+		{query: "Main", kinds: []filterTermKind{termFuzzy}, values: []string{"main"}, negate: []bool{false}},
+		{query: "ext:GO,.ts", kinds: []filterTermKind{termExtension}, values: []string{".go,.ts"}, negate: []bool{false}},
+		{query: "dir:/internal/", kinds: []filterTermKind{termDirectory}, values: []string{"internal"}, negate: []bool{false}},
+		{query: "'exact", kinds: []filterTermKind{termExact}, values: []string{"exact"}, negate: []bool{false}},
+		{query: "^cmd", kinds: []filterTermKind{termPrefix}, values: []string{"cmd"}, negate: []bool{false}},
+		{query: "_test.go$", kinds: []filterTermKind{termSuffix}, values: []string{"_test.go"}, negate: []bool{false}},
+		{query: "^main.go$", kinds: []filterTermKind{termWhole}, values: []string{"main.go"}, negate: []bool{false}},
+		{query: "re:/_v[0-9]+$/", kinds: []filterTermKind{termRegexp}, values: []string{"_v[0-9]+$"}, negate: []bool{false}},
+		{query: "re:_v[0-9", wantErr: "regular expression"},
+		{query: "!_test", kinds: []filterTermKind{termExact}, values: []string{"_test"}, negate: []bool{true}},
+		{query: "!ext:md", kinds: []filterTermKind{termExtension}, values: []string{".md"}, negate: []bool{true}},
+		{
+			query:  "go !vendor ext:go",
+			kinds:  []filterTermKind{termFuzzy, termExact, termExtension},
+			values: []string{"go", "vendor", ".go"},
+			negate: []bool{false, true, false},
+		},
+		// Incomplete terms, as typed, are skipped.
+		{query: "ext: ext:, dir: ' ^ $ ! re: re:/ !re:", kinds: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			terms, err := parseFilterQuery(tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(terms) != len(tt.kinds) {
+				t.Fatalf("got %d term(s) %+v, want %d", len(terms), terms, len(tt.kinds))
+			}
+			for i, term := range terms {
+				value := term.value
+				if term.kind == termExtension {
+					value = strings.Join(term.values, ",")
+				}
+				if term.kind != tt.kinds[i] || value != tt.values[i] || term.negate != tt.negate[i] {
+					t.Errorf("term %d = kind %d %q negate %v, want kind %d %q negate %v",
+						i, term.kind, value, term.negate, tt.kinds[i], tt.values[i], tt.negate[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilterQueryMatchFiles(t *testing.T) {
+	paths := []string{
+		"main.go",
+		"main_test.go",
+		"README.md",
+		"internal/api/handler.go",
+		"internal/api/handler_test.go",
+		"internal/db/schema_v2.sql",
+		"cmd/yank/main.go",
+		"web/app.ts",
+		"web/App.tsx",
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: paths},
+		{query: "ext:go !_test", want: []string{"main.go", "internal/api/handler.go", "cmd/yank/main.go"}},
+		{query: "ext:ts,tsx", want: []string{"web/app.ts", "web/App.tsx"}},
+		{query: "dir:api", want: []string{"internal/api/handler.go", "internal/api/handler_test.go"}},
+		{query: "dir:int", want: nil},
+		{query: "dir:internal/api", want: []string{"internal/api/handler.go", "internal/api/handler_test.go"}},
+		{query: "^main", want: []string{"main.go", "main_test.go"}},
+		{query: "main.go$", want: []string{"main.go", "cmd/yank/main.go"}},
+		{query: "^main.go$", want: []string{"main.go"}},
+		{query: "'readme", want: []string{"README.md"}},
+		{query: "re:/_v[0-9]+\\./", want: []string{"internal/db/schema_v2.sql"}},
+		{query: "hdlr", want: []string{"internal/api/handler.go", "internal/api/handler_test.go"}},
+		{query: "app !ext:ts", want: []string{"web/App.tsx"}},
+		{query: "!ext:go !ext:md !ext:sql !dir:web", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseFilterQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, match := range q.matchFiles(paths) {
+				got = append(got, match.path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matches = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
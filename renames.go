@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Rename Tracking ---
+
+// Sources of a remap candidate, shown next to each entry in the remap prompt.
+const (
+	remapViaGit         = "git rename"
+	remapViaFingerprint = "same content"
+)
+
+// remapCandidate proposes a new path for a saved path that no longer exists.
+type remapCandidate struct {
+	oldPath string // Saved relative path that was not found during the walk.
+	newPath string // Existing relative path the file most likely moved to.
+	via     string // remapViaGit or remapViaFingerprint.
+}
+
+// remapPrompt holds the overlay that offers to re-map stale entries after a start.
+type remapPrompt struct {
+	active     bool             // Flag indicating whether the prompt replaces the file list.
+	candidates []remapCandidate // Proposed re-mappings, in the order of the stale paths.
+}
+
+// computeFingerprint returns the size and SHA-256 of the file at path.
+func computeFingerprint(path string) (fileFingerprint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	return fileFingerprint{Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// recordFingerprints stores fingerprints of relativePaths in state and remembers the checked
+// out commit, so moves can be detected on the next start. Unreadable files are skipped.
+func recordFingerprints(state *selectionState, targetDir string, relativePaths []string) {
+	for _, relativePath := range relativePaths {
+		if fp, err := computeFingerprint(filepath.Join(targetDir, relativePath)); err == nil {
+			state.fingerprints[relativePath] = fp
+		}
+	}
+	if head, err := gitOutput(targetDir, "rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		state.gitHead = head
+	}
+}
+
+// findRenames looks for the new location of every path in stale. Git's rename detection is
+// tried first (against the commit of the last save, including uncommitted `git mv`s); the
+// remaining paths are matched by their stored fingerprint. Ambiguous matches are skipped.
+func findRenames(targetDir string, state selectionState, stale []string, availableFiles []string) []remapCandidate {
+	if len(stale) == 0 {
+		return nil
+	}
+	available := make(map[string]struct{}, len(availableFiles))
+	for _, relativePath := range availableFiles {
+		available[relativePath] = struct{}{}
+	}
+
+	gitRenames := gitRenamedPaths(targetDir, state.gitHead)
+
+	var candidates []remapCandidate
+	var unresolved []string
+	for _, oldPath := range stale {
+		if newPath, ok := gitRenames[oldPath]; ok {
+			if _, exists := available[newPath]; exists {
+				candidates = append(candidates, remapCandidate{oldPath: oldPath, newPath: newPath, via: remapViaGit})
+				continue
+			}
+		}
+		if _, ok := state.fingerprints[oldPath]; ok {
+			unresolved = append(unresolved, oldPath)
+		}
+	}
+	if len(unresolved) == 0 {
+		return candidates
+	}
+
+	// Group the wanted fingerprints by size, so only files of a matching size are hashed.
+	wantedSizes := make(map[int64]struct{})
+	for _, oldPath := range unresolved {
+		wantedSizes[state.fingerprints[oldPath].Size] = struct{}{}
+	}
+	referenced := make(map[string]struct{})
+	for _, relativePath := range state.referencedPaths() {
+		referenced[relativePath] = struct{}{}
+	}
+	byHash := make(map[string][]string) // Key: SHA-256, value: available paths with that content.
+	for _, relativePath := range availableFiles {
+		if _, isSaved := referenced[relativePath]; isSaved {
+			continue // Already part of the saved state; not a move target.
+		}
+		info, err := os.Stat(filepath.Join(targetDir, relativePath))
+		if err != nil {
+			continue
+		}
+		if _, wanted := wantedSizes[info.Size()]; !wanted {
+			continue
+		}
+		fp, err := computeFingerprint(filepath.Join(targetDir, relativePath))
+		if err != nil {
+			continue
+		}
+		byHash[fp.SHA256] = append(byHash[fp.SHA256], relativePath)
+	}
+	for _, oldPath := range unresolved {
+		if matches := byHash[state.fingerprints[oldPath].SHA256]; len(matches) == 1 {
+			candidates = append(candidates, remapCandidate{oldPath: oldPath, newPath: matches[0], via: remapViaFingerprint})
+		}
+	}
+	return candidates
+}
+
+// gitRenamedPaths returns the renames git detects in targetDir between base (or HEAD, if
+// base is empty or unknown) and the working tree. Keys and values are paths relative to
+// targetDir. It returns an empty map outside a git work tree.
+func gitRenamedPaths(targetDir string, base string) map[string]string {
+	renames := make(map[string]string)
+	if base == "" {
+		base = "HEAD"
+	}
+	if _, err := gitOutput(targetDir, "rev-parse", "--verify", "-q", base+"^{commit}"); err != nil {
+		base = "HEAD"
+	}
+	// -z keeps unusual file names intact; --relative limits output to targetDir and makes
+	// paths relative to it.
+	output, err := gitOutput(targetDir, "diff", "-M", "--name-status", "-z", "--relative", base)
+	if err != nil {
+		return renames
+	}
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		switch {
+		case strings.HasPrefix(status, "R") && i+2 < len(fields):
+			renames[filepath.FromSlash(fields[i+1])] = filepath.FromSlash(fields[i+2])
+			i += 2
+		case strings.HasPrefix(status, "C") && i+2 < len(fields):
+			i += 2 // Copies: the original still exists, nothing to re-map.
+		case status != "":
+			i++ // Single-path status: skip the path.
+		}
+	}
+	return renames
+}
+
+// applyRenames replaces every old path of candidates in the current selection and all named
+// sets, moving the stored fingerprints along.
+func applyRenames(state *selectionState, candidates []remapCandidate) {
+	mapping := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		mapping[c.oldPath] = c.newPath
+	}
+	rewrite := func(paths []string) []string {
+		result := make([]string, 0, len(paths))
+		seen := make(map[string]struct{}, len(paths))
+		for _, relativePath := range paths {
+			if newPath, ok := mapping[relativePath]; ok {
+				relativePath = newPath
+			}
+			if _, dup := seen[relativePath]; !dup {
+				seen[relativePath] = struct{}{}
+				result = append(result, relativePath)
+			}
+		}
+		return result
+	}
+	state.current = rewrite(state.current)
+	for name, paths := range state.sets {
+		state.sets[name] = rewrite(paths)
+	}
+	for oldPath, newPath := range mapping {
+		if fp, ok := state.fingerprints[oldPath]; ok {
+			state.fingerprints[newPath] = fp
+			delete(state.fingerprints, oldPath)
+		}
+	}
+}
+
+// --- Remap Prompt ---
+
+// updateRemapPrompt handles key presses while the remap prompt is shown: accepting re-maps
+// the stale entries (and persists the change), declining leaves them out of the selection.
+func (m model) updateRemapPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.RemapAccept):
+		candidates := m.remap.candidates
+		state, err := updateSelectionState(m.targetDir, func(state *selectionState) error {
+			applyRenames(state, candidates)
+			return nil
+		})
+		if err != nil {
+			m.lastErr = err
+			return m, nil
+		}
+		m.state = state
+		m.setBase(m.existingPaths(state.current), state.patterns)
+		m.remap = remapPrompt{}
+		// Stale entries were dropped from the selection on load; select their new paths instead.
+		for _, c := range candidates {
+			if slices.Contains(state.current, c.newPath) {
+				m.selected[c.newPath] = true
+			}
+		}
+		m.refreshListItems()
+		return m, m.setStatus(fmt.Sprintf("Re-mapped %d moved file(s)", len(candidates)))
+
+	case key.Matches(msg, m.keys.RemapDecline):
+		// The stale entries are already absent from the selection; the file is updated on the next save.
+		m.remap = remapPrompt{}
+	}
+	return m, nil
+}
+
+// viewRemapPrompt renders the remap prompt in place of the file list.
+func (m model) viewRemapPrompt() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Saved files were moved:") + "\n\n")
+	for _, c := range m.remap.candidates {
+		sb.WriteString(itemStyle.Render(fmt.Sprintf("  %s -> %s", c.oldPath, checkedStyle.Render(c.newPath))))
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("  (%s)", c.via)) + "\n")
+	}
+	sb.WriteString("\n" + helpStyle.Render(keyHints(m.keys.RemapAccept, m.keys.RemapDecline)))
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	}
+	return docStyle.Render(sb.String())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestApplyRenames(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    []string
+		sets       map[string][]string
+		candidates []remapCandidate
+		want       []string
+		wantSets   map[string][]string
+	}{
+		{
+			name:    "no candidates",
+			current: []string{"a.go", "b.go"},
+			want:    []string{"a.go", "b.go"},
+		},
+		{
+			name:       "renamed in place",
+			current:    []string{"a.go", "old.go", "b.go"},
+			candidates: []remapCandidate{{oldPath: "old.go", newPath: "new/old.go", via: remapViaGit}},
+			want:       []string{"a.go", "new/old.go", "b.go"},
+		},
+		{
+			name:       "moved onto a selected file",
+			current:    []string{"new.go", "old.go"},
+			candidates: []remapCandidate{{oldPath: "old.go", newPath: "new.go", via: remapViaFingerprint}},
+			want:       []string{"new.go"},
+		},
+		{
+			name:    "sets",
+			current: []string{},
+			sets: map[string][]string{
+				"api":   {"api/old.go", "api/keep.go"},
+				"other": {"z.go"},
+			},
+			candidates: []remapCandidate{{oldPath: "api/old.go", newPath: "api/v2/old.go", via: remapViaGit}},
+			want:       []string{},
+			wantSets: map[string][]string{
+				"api":   {"api/v2/old.go", "api/keep.go"},
+				"other": {"z.go"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newSelectionState()
+			state.current = tt.current
+			for name, paths := range tt.sets {
+				state.sets[name] = slices.Clone(paths)
+			}
+			for _, c := range tt.candidates {
+				state.fingerprints[c.oldPath] = fileFingerprint{Size: 1, SHA256: "hash of " + c.oldPath}
+			}
+
+			applyRenames(&state, tt.candidates)
+
+			if !slices.Equal(state.current, tt.want) {
+				t.Errorf("current = %q, want %q", state.current, tt.want)
+			}
+			for name, want := range tt.wantSets {
+				if !slices.Equal(state.sets[name], want) {
+					t.Errorf("set %q = %q, want %q", name, state.sets[name], want)
+				}
+			}
+			for _, c := range tt.candidates {
+				if fp, ok := state.fingerprints[c.newPath]; !ok || fp.SHA256 != "hash of "+c.oldPath {
+					t.Errorf("fingerprint of %q = %+v, want the one of %q", c.newPath, fp, c.oldPath)
+				}
+			}
+		})
+	}
+}
+
+func TestFindRenamesByFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"moved/a.go":  "package a\n",
+		"copy1/b.go":  "package b\n",
+		"copy2/b.go":  "package b\n", // Two candidates: ambiguous, so b.go is not re-mapped.
+		"selected.go": "package c\n", // Same content as c.go, but already part of the selection.
+		"other.go":    "package other\n",
+	}
+	for relativePath, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(relativePath))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fingerprint := func(content string) fileFingerprint {
+		path := filepath.Join(t.TempDir(), "f")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fp, err := computeFingerprint(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+
+	state := newSelectionState()
+	state.current = []string{"a.go", "b.go", "c.go", "d.go", "selected.go"}
+	state.fingerprints["a.go"] = fingerprint("package a\n")
+	state.fingerprints["b.go"] = fingerprint("package b\n")
+	state.fingerprints["c.go"] = fingerprint("package c\n")
+	// d.go has no fingerprint, so it cannot be found.
+
+	available := make([]string, 0, len(files))
+	for relativePath := range files {
+		available = append(available, filepath.FromSlash(relativePath))
+	}
+	slices.Sort(available)
+
+	candidates := findRenames(dir, state, []string{"a.go", "b.go", "c.go", "d.go"}, available)
+	want := []remapCandidate{{oldPath: "a.go", newPath: filepath.FromSlash("moved/a.go"), via: remapViaFingerprint}}
+	if !slices.Equal(candidates, want) {
+		t.Errorf("candidates = %+v, want %+v", candidates, want)
+	}
+}
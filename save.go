@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Saving Without Copying ---
+
+// selectionDiff describes how the selection in the TUI differs from the one on disk.
+type selectionDiff struct {
+	added           []string // Files checked in the TUI but not on disk.
+	removed         []string // Files checked on disk but not in the TUI.
+	patternsAdded   []string // Patterns in the TUI but not on disk.
+	patternsRemoved []string // Patterns on disk but not in the TUI.
+}
+
+// isEmpty reports whether the selections are the same.
+func (d selectionDiff) isEmpty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.patternsAdded) == 0 && len(d.patternsRemoved) == 0
+}
+
+// unsavedChanges compares the explicit selection and patterns with the persistence file.
+// Saved entries of files that no longer exist are ignored, like the loader does. An
+// unreadable file counts as empty, so everything selected is reported as unsaved.
+func (m *model) unsavedChanges() selectionDiff {
+	disk, err := readSelectionState(m.targetDir)
+	if err != nil {
+		disk = newSelectionState()
+	}
+	theirs := m.existingPaths(disk.current)
+	mine := sortedCopy(m.selectedPaths())
+
+	var diff selectionDiff
+	for _, relativePath := range mine {
+		if !slices.Contains(theirs, relativePath) {
+			diff.added = append(diff.added, relativePath)
+		}
+	}
+	for _, relativePath := range theirs {
+		if !slices.Contains(mine, relativePath) {
+			diff.removed = append(diff.removed, relativePath)
+		}
+	}
+	for _, pattern := range m.patterns {
+		if !slices.Contains(disk.patterns, pattern) {
+			diff.patternsAdded = append(diff.patternsAdded, pattern)
+		}
+	}
+	for _, pattern := range disk.patterns {
+		if !slices.Contains(m.patterns, pattern) {
+			diff.patternsRemoved = append(diff.patternsRemoved, pattern)
+		}
+	}
+	return diff
+}
+
+// saveNow persists the selection, patterns and session like a copy does, without copying.
+func (m *model) saveNow() error {
+	explicitPaths := m.selectedPaths()
+	if err := saveSelections(explicitPaths, slices.Clone(m.patterns), m.activeSet, m.sessionSnapshot(), m.targetDir, m.saveBase()); err != nil {
+		return err
+	}
+	m.setBase(m.existingPaths(explicitPaths), m.patterns)
+	return nil
+}
+
+// saveSelection saves the selection and reports the result in the status line. If another
+// instance saved meanwhile, the merge prompt is opened instead.
+func (m *model) saveSelection() tea.Cmd {
+	if err := m.saveNow(); err != nil {
+		if errors.Is(err, errSelectionConflict) && m.openMergePromptForSave(false, 0) {
+			return nil
+		}
+		m.lastErr = fmt.Errorf("saving selection: %w", err)
+		return nil
+	}
+	m.lastErr = nil
+	files := len(m.selectedPaths())
+	status := fmt.Sprintf("Saved %d file(s)", files)
+	if len(m.patterns) > 0 {
+		status = fmt.Sprintf("Saved %d file(s) and %d pattern(s)", files, len(m.patterns))
+	}
+	if m.activeSet != "" {
+		status += fmt.Sprintf(" (also to set '%s')", m.activeSet)
+	}
+	return m.setStatus(status)
+}
+
+// saveAndQuit saves the selection and quits; copiedFiles is the number of files already on the
+// clipboard, for the final log line. If another instance saved meanwhile, the merge prompt is
+// opened instead.
+func (m *model) saveAndQuit(copiedFiles int) tea.Cmd {
+	if err := m.saveNow(); err != nil {
+		if errors.Is(err, errSelectionConflict) && m.openMergePromptForSave(true, copiedFiles) {
+			return nil
+		}
+		m.lastErr = fmt.Errorf("saving selection: %w", err)
+		return nil
+	}
+	if copiedFiles > 0 {
+		log.Printf("Copied %d file(s), saved selection.", copiedFiles)
+	}
+	return m.quit()
+}
+
+// quit ends the program.
+func (m *model) quit() tea.Cmd {
+	m.quitting = true
+	if m.statusTimer != nil {
+		m.statusTimer.Stop() // Clean up status timer if active.
+	}
+	return tea.Quit
+}
+
+// requestQuit quits directly if nothing is unsaved. Otherwise it saves first (with -save-on-quit)
+// or asks what to do with the changes. Like any save, saving on quit never overwrites a selection
+// saved by another instance; the merge prompt resolves it first.
+func (m *model) requestQuit() tea.Cmd {
+	diff := m.unsavedChanges()
+	if diff.isEmpty() {
+		return m.quit()
+	}
+	if m.saveOnQuit {
+		return m.saveAndQuit(0)
+	}
+	m.quitPrompt = quitPrompt{active: true, diff: diff}
+	return nil
+}
+
+// --- Quit Prompt ---
+
+// quitPrompt holds the overlay asking whether to save unsaved selection changes on quit.
+type quitPrompt struct {
+	active bool          // Flag indicating whether the prompt replaces the file list.
+	diff   selectionDiff // Changes that would be lost.
+}
+
+// updateQuitPrompt handles key presses while the quit prompt is shown.
+func (m model) updateQuitPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	// A second ctrl+c discards, so quitting never needs more than two key presses.
+	case msg.Type == tea.KeyCtrlC, key.Matches(msg, m.keys.QuitDiscard):
+		return m, m.quit()
+
+	case key.Matches(msg, m.keys.QuitSave):
+		return m, m.saveAndQuit(0)
+
+	case key.Matches(msg, m.keys.QuitCancel):
+		m.quitPrompt = quitPrompt{}
+		m.lastErr = nil
+	}
+	return m, nil
+}
+
+// viewQuitPrompt renders the quit prompt in place of the file list.
+func (m model) viewQuitPrompt() string {
+	var lines []string
+	for _, relativePath := range m.quitPrompt.diff.added {
+		lines = append(lines, diffAddStyle.Render("  + "+relativePath))
+	}
+	for _, relativePath := range m.quitPrompt.diff.removed {
+		lines = append(lines, diffRemoveStyle.Render("  - "+relativePath))
+	}
+	for _, pattern := range m.quitPrompt.diff.patternsAdded {
+		lines = append(lines, diffAddStyle.Render("  + pattern "+pattern))
+	}
+	for _, pattern := range m.quitPrompt.diff.patternsRemoved {
+		lines = append(lines, diffRemoveStyle.Render("  - pattern "+pattern))
+	}
+	// Keep the prompt on screen for long diffs.
+	if visible := max(m.list.Height()-6, 3); len(lines) > visible {
+		more := len(lines) - visible + 1
+		lines = append(lines[:visible-1], helpStyle.Render(fmt.Sprintf("  ... and %d more", more)))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("The selection differs from the saved one:") + "\n\n")
+	sb.WriteString(strings.Join(lines, "\n") + "\n\n")
+	sb.WriteString(helpStyle.Render(keyHints(m.keys.QuitSave, m.keys.QuitDiscard, m.keys.QuitCancel)))
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	}
+	return docStyle.Render(sb.String())
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Content Search ---
+
+const (
+	searchMaxFileBytes = 16 << 20              // Larger files are not searched.
+	searchBinaryProbe  = 8000                  // Bytes checked for a NUL byte to skip binary files, like git does.
+	searchBatchDelay   = 50 * time.Millisecond // Interval at which results are sent to the TUI.
+)
+
+// contentHit is a file whose content matches the search query.
+type contentHit struct {
+	path  string
+	count int    // Number of matching lines.
+	line  int    // Line number of the first match (1-based).
+	text  string // First matching line, trimmed.
+}
+
+// contentSearch holds the state of the content search mode, the second mode of filtering: the
+// query is searched in the files instead of matched against their paths.
+type contentSearch struct {
+	active     bool               // Flag set while filtering searches contents.
+	generation int                // Number of the current search; results of older ones are dropped.
+	cancel     context.CancelFunc // Stops the running search (nil if none).
+	hits       []contentHit       // Matching files found so far.
+	scanned    int                // Number of files searched so far.
+	done       bool               // Flag set once every file was searched.
+}
+
+// contentSearchMsg carries a batch of results of a running search.
+type contentSearchMsg struct {
+	generation int
+	hits       []contentHit
+	scanned    int // Number of files searched so far (not only in this batch).
+	done       bool
+	results    <-chan contentSearchMsg // Channel of the next batches.
+}
+
+// waitForContentSearch returns the command receiving the next batch of a search.
+func waitForContentSearch(results <-chan contentSearchMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-results
+		if !ok {
+			return nil
+		}
+		msg.results = results
+		return msg
+	}
+}
+
+// newContentMatcher returns a function reporting whether a line contains query. Like ripgrep's
+// smart case, the search ignores case unless the query contains an upper-case letter.
+func newContentMatcher(query string) func(line []byte) bool {
+	if strings.IndexFunc(query, unicode.IsUpper) >= 0 {
+		needle := []byte(query)
+		return func(line []byte) bool { return bytes.Contains(line, needle) }
+	}
+	needle := bytes.ToLower([]byte(query))
+	return func(line []byte) bool { return bytes.Contains(bytes.ToLower(line), needle) }
+}
+
+// searchFile searches one file. Large, unreadable and binary files never match.
+func searchFile(absolutePath string, matches func(line []byte) bool) (contentHit, bool) {
+	info, err := os.Stat(absolutePath)
+	if err != nil || info.Size() > searchMaxFileBytes {
+		return contentHit{}, false
+	}
+	content, err := os.ReadFile(absolutePath)
+	if err != nil || bytes.IndexByte(content[:min(len(content), searchBinaryProbe)], 0) >= 0 {
+		return contentHit{}, false
+	}
+	// Most files do not match at all, so check the whole content before splitting it into lines.
+	if !matches(content) {
+		return contentHit{}, false
+	}
+	var hit contentHit
+	for lineNumber, line := range bytes.Split(content, []byte{'\n'}) {
+		if matches(line) {
+			if hit.count == 0 {
+				hit.line = lineNumber + 1
+				// Tabs and carriage returns would break the row layout.
+				hit.text = strings.TrimSpace(strings.NewReplacer("\t", " ", "\r", "").Replace(string(line)))
+			}
+			hit.count++
+		}
+	}
+	return hit, hit.count > 0
+}
+
+// searchContents searches the files for query on all CPUs and sends the hits to results in
+// batches, closing it when done or cancelled.
+func searchContents(ctx context.Context, targetDir string, files []string, query string, generation int, results chan<- contentSearchMsg) {
+	defer close(results)
+	matches := newContentMatcher(query)
+
+	paths := make(chan string)
+	found := make(chan *contentHit) // nil for a file without a match, to count it as scanned.
+	var workers sync.WaitGroup
+	for range runtime.NumCPU() {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for relativePath := range paths {
+				var result *contentHit
+				if hit, ok := searchFile(filepath.Join(targetDir, relativePath), matches); ok {
+					hit.path = relativePath
+					result = &hit
+				}
+				select {
+				case found <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(paths)
+		for _, relativePath := range files {
+			select {
+			case paths <- relativePath:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(found)
+	}()
+
+	// Collect the hits and send them in batches, so the TUI renders them as they come in
+	// without redrawing for every file.
+	batch := contentSearchMsg{generation: generation}
+	ticker := time.NewTicker(searchBatchDelay)
+	defer ticker.Stop()
+	sentScanned := 0
+	send := func() bool {
+		select {
+		case results <- batch:
+			sentScanned = batch.scanned
+			batch = contentSearchMsg{generation: generation, scanned: batch.scanned}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		select {
+		case hit, ok := <-found:
+			if !ok {
+				batch.done = true
+				send()
+				return
+			}
+			batch.scanned++
+			if hit != nil {
+				batch.hits = append(batch.hits, *hit)
+			}
+		case <-ticker.C:
+			if batch.scanned == sentScanned {
+				continue // Nothing new to show.
+			}
+			if !send() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startContentSearch searches the files for the filter query, replacing a running search.
+func (m *model) startContentSearch() tea.Cmd {
+	m.stopContentSearch()
+	m.search.generation++
+	if m.filterQuery == "" {
+		m.search.done = true
+		m.showContentResults()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.search.cancel = cancel
+	results := make(chan contentSearchMsg)
+	go searchContents(ctx, m.targetDir, m.allAvailableFiles, m.filterQuery, m.search.generation, results)
+	m.showContentResults()
+	return waitForContentSearch(results)
+}
+
+// stopContentSearch cancels the running search and drops its results.
+func (m *model) stopContentSearch() {
+	if m.search.cancel != nil {
+		m.search.cancel()
+	}
+	m.search = contentSearch{active: m.search.active, generation: m.search.generation}
+}
+
+// handleContentSearch adds a batch of results and waits for the next one.
+func (m *model) handleContentSearch(msg contentSearchMsg) tea.Cmd {
+	if !m.search.active || msg.generation != m.search.generation {
+		return nil // A batch of a replaced search; its goroutines stop once cancelled.
+	}
+	m.search.hits = append(m.search.hits, msg.hits...)
+	m.search.scanned = msg.scanned
+	m.search.done = msg.done
+	m.showContentResults()
+	if msg.done {
+		m.search.cancel() // Releases the context; the search has ended already.
+		m.search.cancel = nil
+		return nil
+	}
+	return waitForContentSearch(msg.results)
+}
+
+// showContentResults lists the hits found so far in the order of the sort mode, keeping the
+// cursor on the focused file while results come in.
+func (m *model) showContentResults() {
+	focused := ""
+	if current, ok := m.list.SelectedItem().(item); ok {
+		focused = current.name
+	}
+	hits := slices.Clone(m.search.hits)
+	slices.SortStableFunc(hits, func(a, b contentHit) int { return m.compareFiles(a.path, b.path) })
+
+	items := make([]list.Item, 0, len(hits))
+	cursor := 0
+	for i, hit := range hits {
+		if hit.path == focused {
+			cursor = i
+		}
+		items = append(items, item{name: hit.path, hit: &hits[i]})
+	}
+	m.setListItems(items)
+	m.list.Select(cursor)
+	m.list.Title = fmt.Sprintf("Content matches for '%s'%s:", m.filterQuery, m.sortTitleSuffix())
+}
+
+// setContentSearch switches filtering between matching paths and searching contents; the
+// query is kept.
+func (m *model) setContentSearch(active bool) tea.Cmd {
+	m.stopContentSearch()
+	m.search.active = active
+	if active {
+		m.filterTerms = nil // Content matches are not highlighted in the paths.
+		m.updateDelegate()
+		return m.startContentSearch()
+	}
+	m.applyFilter()
+	return nil
+}
+
+// queryChanged updates the results after the filter query was edited.
+func (m *model) queryChanged() tea.Cmd {
+	if m.search.active {
+		return m.startContentSearch()
+	}
+	m.applyFilter()
+	return nil
+}
+
+// contentHitColumn renders the hit count and the first matching line of a result row.
+func contentHitColumn(hit *contentHit) string {
+	return fmt.Sprintf("%d× %d: %s", hit.count, hit.line, hit.text)
+}
+
+// searchProgress describes the state of the search for the info line.
+func (m *model) searchProgress() string {
+	if m.filterQuery == "" {
+		return "type to search file contents"
+	}
+	files := fmt.Sprintf("%d file(s)", len(m.search.hits))
+	if m.search.done {
+		return files
+	}
+	return fmt.Sprintf("%s, searching %d/%d", files, m.search.scanned, len(m.allAvailableFiles))
+}
+
+// contentHitPaths returns the paths of the hits found so far.
+func (m *model) contentHitPaths() []string {
+	paths := make([]string, 0, len(m.search.hits))
+	for _, hit := range m.search.hits {
+		paths = append(paths, hit.path)
+	}
+	return paths
+}
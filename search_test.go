@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewContentMatcher(t *testing.T) {
+	tests := []struct {
+		query string
+		line  string
+		want  bool
+	}{
+		{query: "todo", line: "// TODO: fix", want: true},  // Lower-case query ignores case.
+		{query: "TODO", line: "// TODO: fix", want: true},  // Upper-case query is exact.
+		{query: "TODO", line: "// todo: fix", want: false}, // ... and does not match other cases.
+		{query: "Parse", line: "func parseBundle()", want: false},
+		{query: "größe", line: "GRÖSSE", want: false},
+		{query: "größe", line: "Größe in Bytes", want: true},
+		{query: "x", line: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := newContentMatcher(tt.query)([]byte(tt.line)); got != tt.want {
+			t.Errorf("matcher(%q)(%q) = %t, want %t", tt.query, tt.line, got, tt.want)
+		}
+	}
+}
+
+// writeSearchFiles creates the files below a temporary directory and returns it.
+func writeSearchFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for relativePath, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(relativePath))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSearchFile(t *testing.T) {
+	dir := writeSearchFiles(t, map[string]string{
+		"main.go":    "package main\n\nfunc main() {\n\t// TODO first\r\n}\n// todo second\n// ToDo third",
+		"none.go":    "package none\n",
+		"binary.bin": "todo\x00todo\n",
+	})
+	// A sparse file just above the limit is skipped without being read.
+	large, err := os.Create(filepath.Join(dir, "large.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := large.Truncate(searchMaxFileBytes + 1); err != nil {
+		t.Fatal(err)
+	}
+	large.Close()
+
+	tests := []struct {
+		name    string
+		file    string
+		wantOK  bool
+		wantHit contentHit
+	}{
+		{name: "count and first line", file: "main.go", wantOK: true, wantHit: contentHit{count: 3, line: 4, text: "// TODO first"}},
+		{name: "no match", file: "none.go"},
+		{name: "binary", file: "binary.bin"},
+		{name: "oversized", file: "large.txt"},
+		{name: "missing", file: "gone.go"},
+	}
+	matches := newContentMatcher("todo")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, ok := searchFile(filepath.Join(dir, tt.file), matches)
+			if ok != tt.wantOK || hit != tt.wantHit {
+				t.Errorf("searchFile(%q) = %+v, %t; want %+v, %t", tt.file, hit, ok, tt.wantHit, tt.wantOK)
+			}
+		})
+	}
+}
+
+// collectContentSearch reads batches until results is closed, failing if that takes too long.
+func collectContentSearch(t *testing.T, results <-chan contentSearchMsg) []contentSearchMsg {
+	t.Helper()
+	var batches []contentSearchMsg
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case msg, ok := <-results:
+			if !ok {
+				return batches
+			}
+			batches = append(batches, msg)
+		case <-timeout:
+			t.Fatal("results channel was not closed")
+		}
+	}
+}
+
+func TestSearchContents(t *testing.T) {
+	files := map[string]string{}
+	var paths []string
+	for i := range 50 {
+		relativePath := fmt.Sprintf("pkg%d/file.go", i)
+		content := "package p\n"
+		if i%10 == 0 {
+			content += "// needle\n"
+		}
+		files[relativePath] = content
+		paths = append(paths, relativePath)
+	}
+	dir := writeSearchFiles(t, files)
+
+	results := make(chan contentSearchMsg)
+	go searchContents(context.Background(), dir, paths, "needle", 7, results)
+	batches := collectContentSearch(t, results)
+
+	if len(batches) == 0 {
+		t.Fatal("no batches received")
+	}
+	last := batches[len(batches)-1]
+	if !last.done || last.scanned != len(paths) {
+		t.Errorf("last batch: done %t, scanned %d; want done after %d files", last.done, last.scanned, len(paths))
+	}
+	var hitPaths []string
+	for _, batch := range batches {
+		if batch.generation != 7 {
+			t.Errorf("batch generation = %d, want 7", batch.generation)
+		}
+		for _, hit := range batch.hits {
+			hitPaths = append(hitPaths, hit.path)
+			if hit.count != 1 || hit.line != 2 || hit.text != "// needle" {
+				t.Errorf("hit %+v, want one match in line 2", hit)
+			}
+		}
+	}
+	slices.Sort(hitPaths)
+	if want := []string{"pkg0/file.go", "pkg10/file.go", "pkg20/file.go", "pkg30/file.go", "pkg40/file.go"}; !slices.Equal(hitPaths, want) {
+		t.Errorf("hits = %q, want %q", hitPaths, want)
+	}
+}
+
+func TestSearchContentsCancel(t *testing.T) {
+	files := map[string]string{}
+	var paths []string
+	for i := range 500 {
+		relativePath := fmt.Sprintf("f%03d.txt", i)
+		files[relativePath] = strings.Repeat("needle\n", 100)
+		paths = append(paths, relativePath)
+	}
+	dir := writeSearchFiles(t, files)
+
+	t.Run("before the first batch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := make(chan contentSearchMsg)
+		go searchContents(ctx, dir, paths, "needle", 1, results)
+		collectContentSearch(t, results)
+	})
+
+	t.Run("while nobody reads", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan contentSearchMsg)
+		go searchContents(ctx, dir, paths, "needle", 1, results)
+		// Take one batch, then stop reading as the TUI does when the query changes.
+		<-results
+		time.Sleep(2 * searchBatchDelay)
+		cancel()
+		for _, batch := range collectContentSearch(t, results) {
+			if batch.done && batch.scanned != len(paths) {
+				t.Errorf("cancelled search reported done after %d of %d files", batch.scanned, len(paths))
+			}
+		}
+	})
+}
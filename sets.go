@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Named Selection Set Picker ---
+
+// setPickerMode distinguishes the sub-states of the selection set picker.
+type setPickerMode int
+
+const (
+	pickerBrowse           setPickerMode = iota // Navigating the list of sets.
+	pickerSaveAs                                // Entering a name to save the current selection as.
+	pickerRename                                // Entering a new name for the focused set.
+	pickerConfirmDelete                         // Waiting for y/n before deleting the focused set.
+	pickerConfirmOverwrite                      // Waiting for y/n before save-as replaces an existing set.
+)
+
+// errSetExists is returned by the save-as update when the name is taken and overwriting
+// has not been confirmed yet.
+var errSetExists = errors.New("selection set already exists")
+
+// setPicker holds the state of the overlay used to load, save, rename, and delete named sets.
+type setPicker struct {
+	active bool            // Flag indicating whether the picker replaces the file list.
+	mode   setPickerMode   // Current sub-state.
+	cursor int             // Index of the focused set name.
+	input  textinput.Model // Name input used by save-as and rename.
+}
+
+// newSetPicker creates a closed picker with an initialised name input.
+func newSetPicker() setPicker {
+	input := textinput.New()
+	input.Prompt = "Name: "
+	input.PromptStyle = filterPromptStyle
+	input.CharLimit = 100
+	return setPicker{input: input}
+}
+
+// openSetPicker shows the picker, focusing the active set if there is one.
+func (m *model) openSetPicker() {
+	m.picker.active = true
+	m.picker.mode = pickerBrowse
+	m.picker.cursor = 0
+	for i, name := range m.state.setNames() {
+		if name == m.activeSet {
+			m.picker.cursor = i
+		}
+	}
+}
+
+// focusedSetName returns the set under the picker cursor, or "" if there are no sets.
+func (m *model) focusedSetName() string {
+	names := m.state.setNames()
+	if m.picker.cursor < 0 || m.picker.cursor >= len(names) {
+		return ""
+	}
+	return names[m.picker.cursor]
+}
+
+// loadSet replaces the current selection with the named set, skipping files that no longer exist.
+func (m *model) loadSet(name string) error {
+	paths, ok := m.state.sets[name]
+	if !ok {
+		return fmt.Errorf("selection set '%s' not found", name)
+	}
+	available := make(map[string]struct{}, len(m.allAvailableFiles))
+	for _, relativePath := range m.allAvailableFiles {
+		available[relativePath] = struct{}{}
+	}
+
+	before := m.snapshotSelection()
+	clear(m.selected)
+	missing := 0
+	for _, relativePath := range paths {
+		if _, exists := available[relativePath]; exists {
+			m.selected[relativePath] = true
+		} else {
+			missing++
+		}
+	}
+	m.activeSet = name
+	m.activePreset = ""
+	m.patterns = slices.Clone(m.state.setPatterns[name])
+	matchPatterns(m.patterns, m.allAvailableFiles, m.patternMatched)
+	m.refreshListItems()
+	m.recordUndo(fmt.Sprintf("load set '%s'", name), before)
+
+	m.statusMessage = fmt.Sprintf("Loaded set '%s' (%d files)", name, len(paths)-missing)
+	if len(m.patterns) > 0 {
+		m.statusMessage += fmt.Sprintf(", %d pattern(s) matching %d", len(m.patterns), len(m.patternMatched))
+	}
+	if missing > 0 {
+		m.statusMessage += fmt.Sprintf(", %d missing", missing)
+	}
+	return nil
+}
+
+// selectedPaths returns the relative paths of all currently selected files.
+func (m *model) selectedPaths() []string {
+	var paths []string
+	for relativePath, selected := range m.selected {
+		if selected {
+			paths = append(paths, relativePath)
+		}
+	}
+	return paths
+}
+
+// updateSetPicker handles key presses while the selection set picker is open.
+func (m model) updateSetPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// ctrl+c always quits, even while typing a name.
+	if msg.Type == tea.KeyCtrlC {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	switch m.picker.mode {
+	case pickerSaveAs, pickerRename:
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.picker.mode = pickerBrowse
+			m.picker.input.Blur()
+			return m, nil
+		case tea.KeyEnter:
+			return m.submitSetName()
+		}
+		var cmd tea.Cmd
+		m.picker.input, cmd = m.picker.input.Update(msg)
+		return m, cmd
+
+	case pickerConfirmOverwrite:
+		if msg.String() == "y" {
+			return m.submitSetName()
+		}
+		// Back to the name input, so another name can be entered.
+		m.picker.mode = pickerSaveAs
+		return m, m.picker.input.Focus()
+
+	case pickerConfirmDelete:
+		m.picker.mode = pickerBrowse
+		if msg.String() != "y" {
+			return m, nil
+		}
+		name := m.focusedSetName()
+		state, err := updateSelectionState(m.targetDir, func(state *selectionState) error {
+			delete(state.sets, name)
+			delete(state.setPatterns, name)
+			return nil
+		})
+		if err != nil {
+			m.lastErr = err
+			return m, nil
+		}
+		m.state = state
+		if m.activeSet == name {
+			m.activeSet = ""
+			m.refreshListItems()
+		}
+		m.picker.cursor = min(m.picker.cursor, max(len(m.state.sets)-1, 0))
+		return m, m.setStatus(fmt.Sprintf("Deleted set '%s'", name))
+	}
+
+	// --- Browse Mode ---
+	names := m.state.setNames()
+	switch {
+	case key.Matches(msg, m.keys.PickerClose):
+		m.picker.active = false
+
+	case key.Matches(msg, m.list.KeyMap.CursorUp):
+		if m.picker.cursor > 0 {
+			m.picker.cursor--
+		}
+
+	case key.Matches(msg, m.list.KeyMap.CursorDown):
+		if m.picker.cursor < len(names)-1 {
+			m.picker.cursor++
+		}
+
+	case key.Matches(msg, m.keys.PickerLoad):
+		if name := m.focusedSetName(); name != "" {
+			m.lastErr = nil
+			if err := m.loadSet(name); err != nil {
+				m.lastErr = err
+				return m, nil
+			}
+			m.picker.active = false
+			return m, clearStatusCmd(2 * time.Second)
+		}
+
+	case key.Matches(msg, m.keys.PickerSaveAs):
+		m.picker.mode = pickerSaveAs
+		m.picker.input.SetValue("")
+		return m, m.picker.input.Focus()
+
+	case key.Matches(msg, m.keys.PickerRename):
+		if name := m.focusedSetName(); name != "" {
+			m.picker.mode = pickerRename
+			m.picker.input.SetValue(name)
+			m.picker.input.CursorEnd()
+			return m, m.picker.input.Focus()
+		}
+
+	case key.Matches(msg, m.keys.PickerDelete):
+		if m.focusedSetName() != "" {
+			m.picker.mode = pickerConfirmDelete
+		}
+	}
+	return m, nil
+}
+
+// submitSetName completes a save-as or rename once the user presses enter in the name input.
+func (m model) submitSetName() (tea.Model, tea.Cmd) {
+	name := strings.TrimSpace(m.picker.input.Value())
+	if err := validateSetName(name); err != nil {
+		m.lastErr = err
+		return m, nil
+	}
+
+	var status string
+	var state selectionState
+	var err error
+	if m.picker.mode == pickerSaveAs || m.picker.mode == pickerConfirmOverwrite {
+		overwrite := m.picker.mode == pickerConfirmOverwrite
+		paths := m.selectedPaths()
+		patterns := slices.Clone(m.patterns)
+		state, err = updateSelectionState(m.targetDir, func(state *selectionState) error {
+			// Checked under the lock, so a set saved meanwhile by another instance counts too.
+			if _, exists := state.sets[name]; exists && !overwrite {
+				return errSetExists
+			}
+			state.sets[name] = paths
+			state.setPatterns[name] = patterns
+			recordFingerprints(state, m.targetDir, paths)
+			return nil
+		})
+		status = fmt.Sprintf("Saved %d file(s) as set '%s'", len(paths), name)
+		if len(patterns) > 0 {
+			status = fmt.Sprintf("Saved %d file(s) and %d pattern(s) as set '%s'", len(paths), len(patterns), name)
+		}
+		if overwrite {
+			status += ", replacing the previous one"
+		}
+		if errors.Is(err, errSetExists) {
+			// Ask before replacing; the picker shows the set list as just read from disk.
+			m.state = state
+			m.picker.mode = pickerConfirmOverwrite
+			m.picker.input.Blur()
+			return m, nil
+		}
+		if err == nil {
+			m.activeSet = name
+			m.activePreset = "" // Sets do not carry a preset's preamble.
+		}
+	} else {
+		oldName := m.focusedSetName()
+		state, err = updateSelectionState(m.targetDir, func(state *selectionState) error {
+			if oldName == name {
+				return nil
+			}
+			if _, exists := state.sets[name]; exists {
+				return fmt.Errorf("selection set '%s' already exists", name)
+			}
+			paths, ok := state.sets[oldName]
+			if !ok {
+				return fmt.Errorf("selection set '%s' not found", oldName)
+			}
+			delete(state.sets, oldName)
+			state.sets[name] = paths
+			if patterns, ok := state.setPatterns[oldName]; ok {
+				delete(state.setPatterns, oldName)
+				state.setPatterns[name] = patterns
+			}
+			return nil
+		})
+		status = fmt.Sprintf("Renamed set '%s' to '%s'", oldName, name)
+		if err == nil && m.activeSet == oldName {
+			m.activeSet = name
+		}
+	}
+	if err != nil {
+		m.lastErr = err
+		return m, nil
+	}
+
+	m.lastErr = nil
+	m.state = state
+	m.picker.mode = pickerBrowse
+	m.picker.input.Blur()
+	for i, n := range m.state.setNames() {
+		if n == name {
+			m.picker.cursor = i
+		}
+	}
+	m.refreshListItems()
+	return m, m.setStatus(status)
+}
+
+// viewSetPicker renders the picker in place of the file list.
+func (m model) viewSetPicker() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Selection sets:") + "\n\n")
+
+	names := m.state.setNames()
+	if len(names) == 0 {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("No named sets yet. Press %s to save the current selection as one.", m.keys.PickerSaveAs.Help().Key)) + "\n")
+	}
+	for i, name := range names {
+		marker := "  "
+		if name == m.activeSet {
+			marker = checkedStyle.Render("* ")
+		}
+		line := fmt.Sprintf("%s (%d files)", name, len(m.state.sets[name]))
+		if n := len(m.state.setPatterns[name]); n > 0 {
+			line = fmt.Sprintf("%s (%d files, %d patterns)", name, len(m.state.sets[name]), n)
+		}
+		if i == m.picker.cursor {
+			sb.WriteString(marker + selectedStyle.Render("> "+line) + "\n")
+		} else {
+			sb.WriteString(marker + itemStyle.Render("  "+line) + "\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	switch m.picker.mode {
+	case pickerSaveAs, pickerRename:
+		sb.WriteString(m.picker.input.View() + "\n")
+		sb.WriteString(helpStyle.Render("enter confirm • esc cancel"))
+	case pickerConfirmDelete:
+		sb.WriteString(errorStyle.Render(fmt.Sprintf("Delete set '%s'? (y/n)", m.focusedSetName())))
+	case pickerConfirmOverwrite:
+		sb.WriteString(m.picker.input.View() + "\n")
+		sb.WriteString(errorStyle.Render(fmt.Sprintf("Set '%s' already exists. Overwrite? (y/n)", strings.TrimSpace(m.picker.input.Value()))))
+	default:
+		sb.WriteString(helpStyle.Render(keyHints(m.keys.PickerLoad, m.keys.PickerSaveAs, m.keys.PickerRename, m.keys.PickerDelete, m.keys.PickerClose)))
+	}
+	if m.lastErr != nil {
+		sb.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.lastErr)))
+	} else if m.statusMessage != "" {
+		sb.WriteString("\n" + helpStyle.Render(m.statusMessage))
+	}
+	return docStyle.Render(sb.String())
+}
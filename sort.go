@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// --- Sort Modes ---
+
+// Sort modes of the file list, persisted with the session.
+const (
+	sortModePath          = "path"     // Directory walk order (default).
+	sortModeBasename      = "name"     // File name, ignoring the directory.
+	sortModeExtension     = "ext"      // File extension, then path.
+	sortModeModified      = "mtime"    // Modification time, newest first.
+	sortModeSize          = "size"     // File size, largest first.
+	sortModeSelectedFirst = "selected" // Selected files (explicitly or by a pattern) first.
+)
+
+// sortModes lists the sort modes in the order the sort key cycles through them.
+var sortModes = []string{sortModePath, sortModeBasename, sortModeExtension, sortModeModified, sortModeSize, sortModeSelectedFirst}
+
+// sortModeLabels describe the sort modes in the title and status line.
+var sortModeLabels = map[string]string{
+	sortModePath:          "path",
+	sortModeBasename:      "file name",
+	sortModeExtension:     "extension",
+	sortModeModified:      "modification time, newest first",
+	sortModeSize:          "size, largest first",
+	sortModeSelectedFirst: "selected first",
+}
+
+// compareFiles orders two paths by the current sort mode. Ties, and the path mode itself, fall
+// back to the directory walk order of the scan.
+func (m *model) compareFiles(a, b string) int {
+	var c int
+	switch m.sortMode {
+	case sortModeBasename:
+		c = strings.Compare(strings.ToLower(filepath.Base(a)), strings.ToLower(filepath.Base(b)))
+	case sortModeExtension:
+		c = strings.Compare(strings.ToLower(filepath.Ext(a)), strings.ToLower(filepath.Ext(b)))
+	case sortModeModified:
+		c = m.fileStats.stat(b).modTime.Compare(m.fileStats.stat(a).modTime)
+	case sortModeSize:
+		c = cmp.Compare(m.fileStats.stat(b).size, m.fileStats.stat(a).size)
+	case sortModeSelectedFirst:
+		aSelected := m.selected[a] || m.patternMatched[a]
+		bSelected := m.selected[b] || m.patternMatched[b]
+		if aSelected != bSelected {
+			c = 1
+			if aSelected {
+				c = -1
+			}
+		}
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(m.walkOrder[a], m.walkOrder[b])
+}
+
+// sortedFiles returns the scanned files in the order of the current sort mode.
+func (m *model) sortedFiles() []string {
+	if m.sortMode == sortModePath {
+		return m.allAvailableFiles
+	}
+	files := slices.Clone(m.allAvailableFiles)
+	slices.SortStableFunc(files, m.compareFiles)
+	return files
+}
+
+// indexWalkOrder remembers the scan position of every file as the final tie breaker.
+func (m *model) indexWalkOrder() {
+	m.walkOrder = make(map[string]int, len(m.allAvailableFiles))
+	for i, relativePath := range m.allAvailableFiles {
+		m.walkOrder[relativePath] = i
+	}
+}
+
+// cycleSortMode switches to the next sort mode and re-sorts the list or filter results.
+// Cached sizes and modification times are read again, so files edited since they were
+// first shown sort by their current metadata.
+func (m *model) cycleSortMode() string {
+	i := slices.Index(sortModes, m.sortMode)
+	m.sortMode = sortModes[(i+1)%len(sortModes)]
+	m.fileStats.invalidate()
+	if m.isFiltering {
+		m.applyFilter()
+	} else {
+		m.refreshListItems()
+	}
+	return "Sorted by " + sortModeLabels[m.sortMode]
+}
+
+// sortTitleSuffix names a non-default sort mode in the list title.
+func (m *model) sortTitleSuffix() string {
+	if m.sortMode == sortModePath {
+		return ""
+	}
+	return " (by " + sortModeLabels[m.sortMode] + ")"
+}
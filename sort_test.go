@@ -0,0 +1,62 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortedFiles(t *testing.T) {
+	files := []string{"src/b.go", "README.md", "src/a_test.go", "docs/a.txt", "Makefile"}
+	base := time.Date(2025, 5, 2, 12, 0, 0, 0, time.UTC)
+	stats := map[string]fileStat{
+		"src/b.go":      {size: 300, modTime: base.Add(2 * time.Hour)},
+		"README.md":     {size: 100, modTime: base},
+		"src/a_test.go": {size: 300, modTime: base.Add(time.Hour)},
+		"docs/a.txt":    {size: 50, modTime: base.Add(2 * time.Hour)},
+		"Makefile":      {size: 20, modTime: base.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{mode: sortModePath, want: files},
+		{mode: sortModeBasename, want: []string{"docs/a.txt", "src/a_test.go", "src/b.go", "Makefile", "README.md"}},
+		// Files with the same extension keep their walk order.
+		{mode: sortModeExtension, want: []string{"Makefile", "src/b.go", "src/a_test.go", "README.md", "docs/a.txt"}},
+		{mode: sortModeModified, want: []string{"src/b.go", "docs/a.txt", "src/a_test.go", "README.md", "Makefile"}},
+		{mode: sortModeSize, want: []string{"src/b.go", "src/a_test.go", "README.md", "docs/a.txt", "Makefile"}},
+		{mode: sortModeSelectedFirst, want: []string{"README.md", "docs/a.txt", "src/b.go", "src/a_test.go", "Makefile"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			m := model{
+				sortMode:          tt.mode,
+				allAvailableFiles: files,
+				selected:          map[string]bool{"README.md": true},
+				patternMatched:    map[string]bool{"docs/a.txt": true},
+				fileStats:         &fileStatCache{stats: maps.Clone(stats)},
+			}
+			m.indexWalkOrder()
+			if got := m.sortedFiles(); !slices.Equal(got, tt.want) {
+				t.Errorf("sortedFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCycleSortModeRereadsStats(t *testing.T) {
+	m := newTestModel(t, "a.go", "b.go")
+	m.fileStats.stats["a.go"] = fileStat{size: 1 << 20} // Stale: a.go is empty on disk.
+	m.sortMode = sortModeModified                       // The next mode sorts by size.
+
+	m.cycleSortMode()
+	if m.sortMode != sortModeSize {
+		t.Fatalf("sort mode = %q, want %q", m.sortMode, sortModeSize)
+	}
+	if got := m.fileStats.stat("a.go").size; got != 0 {
+		t.Errorf("size of a.go = %d, want the current size 0", got)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// --- State Location ---
+
+// Storage modes for the persistence file, selected with -state or $YANK_STATE.
+const (
+	stateModeTree   = "tree"    // persistenceDotFileName inside the scanned directory (default).
+	stateModeXDG    = "xdg"     // $XDG_STATE_HOME/yank/, keyed by the absolute directory path.
+	stateModeXDGGit = "xdg-git" // $XDG_STATE_HOME/yank/, keyed by git remote plus path inside the repository.
+)
+
+// stateMode selects where getPersistenceFilePath stores selection state.
+// It is set once from the command line before any state is read.
+var stateMode = stateModeTree
+
+// defaultStateMode returns the storage mode from $YANK_STATE, or stateModeTree if unset.
+func defaultStateMode() string {
+	if mode := os.Getenv("YANK_STATE"); mode != "" {
+		return mode
+	}
+	return stateModeTree
+}
+
+// validateStateMode checks a user-supplied storage mode.
+func validateStateMode(mode string) error {
+	switch mode {
+	case stateModeTree, stateModeXDG, stateModeXDGGit:
+		return nil
+	}
+	return fmt.Errorf("unknown state mode '%s' (use %s, %s or %s)", mode, stateModeTree, stateModeXDG, stateModeXDGGit)
+}
+
+// treeStateFilePath returns the in-tree persistence file path of targetDir.
+func treeStateFilePath(targetDir string) string {
+	return filepath.Join(targetDir, persistenceDotFileName)
+}
+
+// xdgStateDir returns the directory holding per-directory state files outside the project tree.
+func xdgStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	if runtime.GOOS == "windows" {
+		// On Windows the closest equivalent is %LocalAppData%.
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, appName, "state"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", appName), nil
+}
+
+// stateKeyCache memoizes stateKey, which may run git, per target directory.
+var stateKeyCache sync.Map
+
+// stateKey returns the identity used to key state outside the tree: the absolute path, or
+// for stateModeXDGGit the origin remote URL plus the path inside the repository (so all
+// clones of a repository share their selections). It falls back to the absolute path.
+func stateKey(targetDir string) string {
+	if stateMode != stateModeXDGGit {
+		return targetDir
+	}
+	if key, ok := stateKeyCache.Load(targetDir); ok {
+		return key.(string)
+	}
+	key := gitStateKey(targetDir)
+	stateKeyCache.Store(targetDir, key)
+	return key
+}
+
+// gitStateKey computes the git-based state key of targetDir (see stateKey).
+func gitStateKey(targetDir string) string {
+	remote, err := gitOutput(targetDir, "config", "--get", "remote.origin.url")
+	if err != nil || remote == "" {
+		return targetDir
+	}
+	prefix, err := gitOutput(targetDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return targetDir
+	}
+	return remote + "#" + strings.TrimSuffix(prefix, "/")
+}
+
+// xdgStateFilePath returns the out-of-tree state file for targetDir. The file name combines
+// the directory's base name (for humans) with a hash of the state key (for uniqueness).
+func xdgStateFilePath(targetDir string) (string, error) {
+	dir, err := xdgStateDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(stateKey(targetDir)))
+	return filepath.Join(dir, fmt.Sprintf("%s-%x%s", filepath.Base(targetDir), sum[:8], persistenceDotFileName)), nil
+}
+
+// migrateTreeState moves an existing in-tree persistence file to the out-of-tree location
+// when an out-of-tree mode is active and no out-of-tree state exists yet. It returns the
+// new location if a migration happened.
+func migrateTreeState(targetDir string) (string, error) {
+	if stateMode == stateModeTree {
+		return "", nil
+	}
+	treePath := treeStateFilePath(targetDir)
+	content, err := os.ReadFile(treePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading '%s' for migration: %w", treePath, err)
+	}
+
+	statePath := getPersistenceFilePath(targetDir)
+	if _, err := os.Stat(statePath); err == nil {
+		// Both exist: keep the out-of-tree state and leave the old file for the user to inspect.
+		log.Printf("Note: both '%s' and '%s' exist; using the latter. Remove the in-tree file when no longer needed.", treePath, statePath)
+		return "", nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0750); err != nil {
+		return "", fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := writeFileAtomic(statePath, content, 0640); err != nil {
+		return "", fmt.Errorf("migrating state to '%s': %w", statePath, err)
+	}
+	if err := os.Remove(treePath); err != nil {
+		return "", fmt.Errorf("removing migrated '%s': %w", treePath, err)
+	}
+	return statePath, nil
+}
+
+// --- Git Exclusion ---
+
+// gitExcludedCache records the target directories ensureGitExcluded has already handled, so
+// saves after the first one do not run git again.
+var gitExcludedCache sync.Map
+
+// ensureGitExcluded makes sure the in-tree state files of targetDir are excluded from git
+// (see addGitExcludes). The check runs once per target directory; after a failure it is
+// retried with the next save.
+func ensureGitExcluded(targetDir string) error {
+	if _, checked := gitExcludedCache.LoadOrStore(targetDir, true); checked {
+		return nil
+	}
+	err := addGitExcludes(targetDir)
+	if err != nil {
+		gitExcludedCache.Delete(targetDir)
+	}
+	return err
+}
+
+// addGitExcludes adds the in-tree persistence file, its lock file and the temporary files of
+// atomic writes to .git/info/exclude, so they do not show up in `git status`. It does nothing
+// outside a git work tree or when git is not installed; files already ignored or tracked are
+// left alone. The presets file (.yank.toml) is meant to be committed, so the patterns name the
+// files exactly instead of excluding ".yank*".
+func addGitExcludes(targetDir string) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+	// --show-prefix is the directory's path relative to the work tree root ("" at the root).
+	prefix, err := gitOutput(targetDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil // Not inside a git work tree.
+	}
+
+	var patterns []string
+	for _, name := range []string{persistenceDotFileName, persistenceDotFileName + ".lock", persistenceDotFileName + ".tmp-*"} {
+		// check-ignore exits 0 if ignored; ls-files --error-unmatch exits 0 if tracked. The
+		// temporary files are checked with a sample name.
+		sample := strings.ReplaceAll(name, "*", "0")
+		if _, err := gitOutput(targetDir, "check-ignore", "-q", sample); err == nil {
+			continue
+		}
+		if _, err := gitOutput(targetDir, "ls-files", "--error-unmatch", sample); err == nil {
+			continue
+		}
+		// Anchor the pattern to this directory so only the state files of yank are excluded.
+		patterns = append(patterns, "/"+escapeGitignore(prefix)+name)
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	excludePath, err := gitOutput(targetDir, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(excludePath) {
+		excludePath = filepath.Join(targetDir, excludePath)
+	}
+
+	existing, err := os.ReadFile(excludePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var entry string
+	for _, pattern := range patterns {
+		if !present[pattern] {
+			entry += pattern + "\n"
+		}
+	}
+	if entry == "" {
+		return nil
+	}
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		entry = "\n" + entry
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(excludePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(entry)
+	return err
+}
+
+// escapeGitignore escapes a literal path for use in a gitignore pattern: the glob characters
+// "*", "?", "[" and the backslash everywhere, and "#" and "!" at the start of a path segment.
+func escapeGitignore(slashPath string) string {
+	var sb strings.Builder
+	segmentStart := true
+	for _, r := range slashPath {
+		if strings.ContainsRune(`*?[\`, r) || (segmentStart && (r == '#' || r == '!')) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+		segmentStart = r == '/'
+	}
+	return sb.String()
+}
+
+// gitOutput runs git in dir and returns its trimmed standard output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
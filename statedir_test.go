@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeGitignore(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: ""},
+		{path: "internal/auth/", want: "internal/auth/"},
+		{path: "a*b/c?d/[x]/", want: `a\*b/c\?d/\[x]/`},
+		{path: `back\slash/`, want: `back\\slash/`},
+		{path: "#notes/!important/a#b!/", want: `\#notes/\!important/a#b!/`},
+	}
+	for _, tt := range tests {
+		if got := escapeGitignore(tt.path); got != tt.want {
+			t.Errorf("escapeGitignore(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// newTestGitRepo initialises a git repository in a temporary directory, isolated from the
+// user's git configuration. The test is skipped if git is not installed.
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	return dir
+}
+
+func TestAddGitExcludes(t *testing.T) {
+	repo := newTestGitRepo(t)
+	// The glob characters in the directory name must not widen the exclusion to "weXrd".
+	targetDir := filepath.Join(repo, "we[ir]d*", "#sub")
+	sibling := filepath.Join(repo, "weid", "#sub")
+	for _, dir := range []string{targetDir, sibling} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, persistenceDotFileName+".lock"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(dir string, args ...string) error {
+		return exec.Command("git", append([]string{"-C", dir}, args...)...).Run()
+	}
+	if err := run(repo, "add", "-f", filepath.Join(targetDir, persistenceDotFileName+".lock")); err != nil {
+		t.Fatalf("git add: %v", err)
+	}
+
+	for range 2 { // The second run finds everything excluded already and appends nothing.
+		if err := addGitExcludes(targetDir); err != nil {
+			t.Fatalf("addGitExcludes: %v", err)
+		}
+	}
+
+	exclude, err := os.ReadFile(filepath.Join(repo, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var added []string
+	for _, line := range strings.Split(string(exclude), "\n") {
+		if strings.Contains(line, persistenceDotFileName) {
+			added = append(added, line)
+		}
+	}
+	// The tracked lock file is left alone.
+	want := []string{`/we\[ir]d\*/\#sub/.yank`, `/we\[ir]d\*/\#sub/.yank.tmp-*`}
+	if strings.Join(added, "\n") != strings.Join(want, "\n") {
+		t.Errorf("exclude entries = %q, want %q", added, want)
+	}
+
+	if err := run(targetDir, "check-ignore", "-q", persistenceDotFileName); err != nil {
+		t.Errorf("%s in the target directory is not ignored", persistenceDotFileName)
+	}
+	if err := run(targetDir, "check-ignore", "-q", persistenceDotFileName+".tmp-123"); err != nil {
+		t.Errorf("temporary state file in the target directory is not ignored")
+	}
+	if err := run(sibling, "check-ignore", "-q", persistenceDotFileName); err == nil {
+		t.Errorf("%s in another directory is ignored too", persistenceDotFileName)
+	}
+}
+
+func TestMigrateTreeState(t *testing.T) {
+	previousMode := stateMode
+	t.Cleanup(func() { stateMode = previousMode })
+	stateMode = stateModeXDG
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	targetDir := t.TempDir()
+	treePath := treeStateFilePath(targetDir)
+	content := []byte("main.go\n")
+	if err := os.WriteFile(treePath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	migratedTo, err := migrateTreeState(targetDir)
+	if err != nil {
+		t.Fatalf("migrateTreeState: %v", err)
+	}
+	if migratedTo != getPersistenceFilePath(targetDir) {
+		t.Errorf("migrated to %q, want %q", migratedTo, getPersistenceFilePath(targetDir))
+	}
+	if got, err := os.ReadFile(migratedTo); err != nil || string(got) != string(content) {
+		t.Errorf("migrated content = %q, %v; want %q", got, err, content)
+	}
+	if _, err := os.Stat(treePath); !os.IsNotExist(err) {
+		t.Errorf("in-tree file still exists after the migration (stat error %v)", err)
+	}
+
+	// With state in both places, the out-of-tree one wins and the in-tree file is kept.
+	if err := os.WriteFile(treePath, []byte("other.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if migratedTo, err := migrateTreeState(targetDir); err != nil || migratedTo != "" {
+		t.Errorf("second migration = %q, %v; want nothing migrated", migratedTo, err)
+	}
+	if _, err := os.Stat(treePath); err != nil {
+		t.Errorf("in-tree file was removed although nothing was migrated: %v", err)
+	}
+	if got, _ := os.ReadFile(getPersistenceFilePath(targetDir)); string(got) != string(content) {
+		t.Errorf("out-of-tree state = %q, want it untouched %q", got, content)
+	}
+
+	// Nothing happens in tree mode.
+	stateMode = stateModeTree
+	if migratedTo, err := migrateTreeState(targetDir); err != nil || migratedTo != "" {
+		t.Errorf("migration in tree mode = %q, %v; want nothing migrated", migratedTo, err)
+	}
+}
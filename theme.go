@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Themes ---
+
+// colorRoles are the themable colours, as named in the [colors] table of the user config.
+var colorRoles = []string{
+	"title",      // List and dialog titles.
+	"cursor",     // The focused row.
+	"checked",    // Checkbox of explicitly selected files.
+	"pattern",    // Checkbox of files selected by a pattern.
+	"help",       // Hints, notes and the info line.
+	"error",      // Errors.
+	"prompt",     // Filter prompt and mode indicators.
+	"match",      // Characters of a path matched by the filter query.
+	"detail",     // Detail columns of the rows.
+	"visual",     // Background of the visual range.
+	"border",     // Border between the list and the preview.
+	"diffHunk",   // Hunk headers of unyank diffs.
+	"diffAdd",    // Added lines of unyank diffs.
+	"diffRemove", // Removed lines of unyank diffs.
+}
+
+// theme is a colour palette for the terminal UI.
+type theme struct {
+	colors  map[string]string // Colour per role: an ANSI colour number (0-255) or #rrggbb.
+	preview string            // Chroma style of the file preview ("" disables highlighting).
+	markers bool              // Flag to mark the cursor row and the visual range with characters too.
+}
+
+// themes are the built-in palettes. Dark is the default; the light palette keeps enough
+// contrast on white backgrounds, and high-contrast sticks to the bright basic colours.
+var themes = map[string]theme{
+	"dark": {
+		colors: map[string]string{
+			"title": "62", "cursor": "75", "checked": "42", "pattern": "37", "help": "240", "error": "196",
+			"prompt": "205", "match": "214", "detail": "244", "visual": "237", "border": "240",
+			"diffHunk": "75", "diffAdd": "42", "diffRemove": "196",
+		},
+		preview: "monokai",
+	},
+	"light": {
+		colors: map[string]string{
+			"title": "55", "cursor": "25", "checked": "28", "pattern": "30", "help": "242", "error": "160",
+			"prompt": "162", "match": "166", "detail": "242", "visual": "254", "border": "248",
+			"diffHunk": "25", "diffAdd": "28", "diffRemove": "160",
+		},
+		preview: "github",
+	},
+	"high-contrast": {
+		colors: map[string]string{
+			"title": "15", "cursor": "14", "checked": "10", "pattern": "11", "help": "7", "error": "9",
+			"prompt": "13", "match": "11", "detail": "7", "visual": "15", "border": "15",
+			"diffHunk": "14", "diffAdd": "10", "diffRemove": "9",
+		},
+		preview: "modus-vivendi",
+		markers: true,
+	},
+}
+
+// defaultThemeName is used when the config names no theme.
+const defaultThemeName = "dark"
+
+// hexColorRegexp matches the #rgb and #rrggbb colour forms.
+var hexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validateColor checks a colour value of the config.
+func validateColor(value string) error {
+	if hexColorRegexp.MatchString(value) {
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 255 {
+		return nil
+	}
+	return fmt.Errorf("invalid colour '%s' (use an ANSI colour number 0-255 or #rrggbb)", value)
+}
+
+// resolveTheme combines the named built-in theme with the colour and preview style overrides.
+// NO_COLOR (https://no-color.org) switches to the markers and turns off preview highlighting;
+// lipgloss drops the colours itself.
+func resolveTheme(name string, colors map[string]string, previewStyle string) (theme, error) {
+	if name == "" {
+		name = defaultThemeName
+	}
+	base, ok := themes[name]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme '%s' (use %s)", name, strings.Join(slices.Sorted(maps.Keys(themes)), ", "))
+	}
+	t := theme{colors: maps.Clone(base.colors), preview: base.preview, markers: base.markers}
+	for _, role := range slices.Sorted(maps.Keys(colors)) {
+		if !slices.Contains(colorRoles, role) {
+			return theme{}, fmt.Errorf("unknown colour '%s' (use %s)", role, strings.Join(colorRoles, ", "))
+		}
+		if err := validateColor(colors[role]); err != nil {
+			return theme{}, fmt.Errorf("colour '%s': %w", role, err)
+		}
+		t.colors[role] = colors[role]
+	}
+	if previewStyle != "" {
+		if _, ok := styles.Registry[previewStyle]; !ok {
+			return theme{}, fmt.Errorf("unknown preview style '%s'", previewStyle)
+		}
+		t.preview = previewStyle
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		t.preview = ""
+		t.markers = true
+	}
+	return t, nil
+}
+
+// markRows is set by applyTheme when rows carry character markers for the cursor and range.
+var markRows bool
+
+// applyTheme sets the styles of the terminal UI. It runs once at startup, before anything is
+// rendered.
+func applyTheme(t theme) {
+	color := func(role string) lipgloss.Color { return lipgloss.Color(t.colors[role]) }
+
+	titleStyle = lipgloss.NewStyle().MarginLeft(0).Bold(true).Foreground(color("title"))
+	selectedStyle = lipgloss.NewStyle().PaddingLeft(0).Foreground(color("cursor")).Bold(true)
+	checkedStyle = lipgloss.NewStyle().Foreground(color("checked"))
+	patternStyle = lipgloss.NewStyle().Foreground(color("pattern"))
+	helpStyle = lipgloss.NewStyle().Foreground(color("help"))
+	errorStyle = lipgloss.NewStyle().Foreground(color("error"))
+	filterPromptStyle = lipgloss.NewStyle().Foreground(color("prompt"))
+	matchStyle = lipgloss.NewStyle().Foreground(color("match")).Bold(true)
+	detailStyle = lipgloss.NewStyle().Foreground(color("detail"))
+	previewBorderStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).BorderForeground(color("border")).
+		PaddingLeft(1)
+	diffHunkStyle = lipgloss.NewStyle().Foreground(color("diffHunk"))
+	diffAddStyle = lipgloss.NewStyle().Foreground(color("diffAdd"))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(color("diffRemove"))
+
+	// Reverse video marks the visual range without relying on a background colour (without any
+	// colours, NO_COLOR, lipgloss drops it and only the row markers remain).
+	visualStyle = lipgloss.NewStyle().Background(color("visual"))
+	if t.markers {
+		visualStyle = lipgloss.NewStyle().Reverse(true)
+	}
+	previewStyleName = t.preview
+	markRows = t.markers
+}
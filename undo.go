@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Undo/Redo ---
+
+// undoMaxEntries limits how many selection changes can be undone.
+const undoMaxEntries = 100
+
+// selectionSnapshot captures everything that decides which files a copy includes.
+type selectionSnapshot struct {
+	selected     []string // Explicitly checked files, sorted.
+	patterns     []string // Selection patterns in order.
+	activeSet    string   // Loaded selection set.
+	activePreset string   // Loaded team preset.
+}
+
+// equal reports whether two snapshots describe the same selection.
+func (s selectionSnapshot) equal(other selectionSnapshot) bool {
+	return slices.Equal(s.selected, other.selected) && slices.Equal(s.patterns, other.patterns) &&
+		s.activeSet == other.activeSet && s.activePreset == other.activePreset
+}
+
+// undoEntry is one recorded selection change.
+type undoEntry struct {
+	description string            // What the change did, e.g. "clear selection".
+	snapshot    selectionSnapshot // Selection on the other side of the change.
+}
+
+// undoHistory holds the undo and redo stacks. Each entry of undo holds the selection before
+// a change; each entry of redo the selection after an undone change.
+type undoHistory struct {
+	undo []undoEntry
+	redo []undoEntry
+}
+
+// snapshotSelection captures the current selection.
+func (m *model) snapshotSelection() selectionSnapshot {
+	return selectionSnapshot{
+		selected:     sortedCopy(m.selectedPaths()),
+		patterns:     slices.Clone(m.patterns),
+		activeSet:    m.activeSet,
+		activePreset: m.activePreset,
+	}
+}
+
+// restoreSnapshot replaces the selection with a snapshot and refreshes the list.
+func (m *model) restoreSnapshot(s selectionSnapshot) {
+	clear(m.selected)
+	for _, relativePath := range s.selected {
+		m.selected[relativePath] = true
+	}
+	m.patterns = slices.Clone(s.patterns)
+	m.activeSet = s.activeSet
+	m.activePreset = s.activePreset
+	m.recomputePatternMatches()
+}
+
+// recordUndo records a selection change made since before was taken. Changes that left the
+// selection as it was are not recorded. Recording a change discards the redo stack.
+func (m *model) recordUndo(description string, before selectionSnapshot) {
+	if before.equal(m.snapshotSelection()) {
+		return
+	}
+	m.undoHistory.undo = append(m.undoHistory.undo, undoEntry{description: description, snapshot: before})
+	if len(m.undoHistory.undo) > undoMaxEntries {
+		m.undoHistory.undo = slices.Delete(m.undoHistory.undo, 0, len(m.undoHistory.undo)-undoMaxEntries)
+	}
+	m.undoHistory.redo = nil
+}
+
+// undo reverts the last recorded selection change.
+func (m *model) undo() tea.Cmd {
+	if len(m.undoHistory.undo) == 0 {
+		return m.setStatus("Nothing to undo")
+	}
+	last := len(m.undoHistory.undo) - 1
+	entry := m.undoHistory.undo[last]
+	m.undoHistory.undo = m.undoHistory.undo[:last]
+	m.undoHistory.redo = append(m.undoHistory.redo, undoEntry{description: entry.description, snapshot: m.snapshotSelection()})
+	m.restoreSnapshot(entry.snapshot)
+	return m.setStatus(fmt.Sprintf("Undid: %s", entry.description))
+}
+
+// redo re-applies the last undone selection change.
+func (m *model) redo() tea.Cmd {
+	if len(m.undoHistory.redo) == 0 {
+		return m.setStatus("Nothing to redo")
+	}
+	last := len(m.undoHistory.redo) - 1
+	entry := m.undoHistory.redo[last]
+	m.undoHistory.redo = m.undoHistory.redo[:last]
+	m.undoHistory.undo = append(m.undoHistory.undo, undoEntry{description: entry.description, snapshot: m.snapshotSelection()})
+	m.restoreSnapshot(entry.snapshot)
+	return m.setStatus(fmt.Sprintf("Redid: %s", entry.description))
+}
+
+// toggleDescription describes checking or unchecking a single file.
+func toggleDescription(relativePath string, selected bool) string {
+	if selected {
+		return "select " + relativePath
+	}
+	return "deselect " + relativePath
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestModel creates a model for a temporary directory holding the given (empty) files.
+func newTestModel(t *testing.T, files ...string) model {
+	t.Helper()
+	dir := t.TempDir()
+	for _, relativePath := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(relativePath))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := initialModel(dir, defaultKeyMap())
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	return m
+}
+
+// toggleForTest checks or unchecks a file the way the toggle key does, recording undo.
+func toggleForTest(m *model, relativePath string) {
+	before := m.snapshotSelection()
+	m.selected[relativePath] = !m.selected[relativePath]
+	m.recordUndo(toggleDescription(relativePath, m.selected[relativePath]), before)
+}
+
+func TestUndoRedo(t *testing.T) {
+	m := newTestModel(t, "a.go", "b.go")
+
+	toggleForTest(&m, "a.go")
+	toggleForTest(&m, "b.go")
+	if got := sortedCopy(m.selectedPaths()); !slices.Equal(got, []string{"a.go", "b.go"}) {
+		t.Fatalf("selected = %q", got)
+	}
+
+	m.undo()
+	if got := m.selectedPaths(); !slices.Equal(got, []string{"a.go"}) {
+		t.Errorf("after undo, selected = %q, want [a.go]", got)
+	}
+	if want := "Undid: select b.go"; m.statusMessage != want {
+		t.Errorf("status = %q, want %q", m.statusMessage, want)
+	}
+
+	m.undo()
+	if got := m.selectedPaths(); len(got) != 0 {
+		t.Errorf("after second undo, selected = %q, want none", got)
+	}
+	m.undo()
+	if want := "Nothing to undo"; m.statusMessage != want {
+		t.Errorf("status = %q, want %q", m.statusMessage, want)
+	}
+
+	m.redo()
+	if got := m.selectedPaths(); !slices.Equal(got, []string{"a.go"}) {
+		t.Errorf("after redo, selected = %q, want [a.go]", got)
+	}
+	if want := "Redid: select a.go"; m.statusMessage != want {
+		t.Errorf("status = %q, want %q", m.statusMessage, want)
+	}
+}
+
+func TestUndoPatternsAndActiveSet(t *testing.T) {
+	m := newTestModel(t, "a.go", "b_test.go")
+
+	before := m.snapshotSelection()
+	m.patterns = []string{"**/*.go"}
+	m.activeSet = "api"
+	m.recomputePatternMatches()
+	m.recordUndo("load set 'api'", before)
+
+	m.undo()
+	if len(m.patterns) != 0 || m.activeSet != "" || len(m.patternMatched) != 0 {
+		t.Errorf("after undo: patterns %q, set %q, %d matched; want all cleared", m.patterns, m.activeSet, len(m.patternMatched))
+	}
+	m.redo()
+	if !slices.Equal(m.patterns, []string{"**/*.go"}) || m.activeSet != "api" || len(m.patternMatched) != 2 {
+		t.Errorf("after redo: patterns %q, set %q, %d matched", m.patterns, m.activeSet, len(m.patternMatched))
+	}
+}
+
+func TestRedoClearedByNewChange(t *testing.T) {
+	m := newTestModel(t, "a.go", "b.go")
+
+	toggleForTest(&m, "a.go")
+	m.undo()
+	if len(m.undoHistory.redo) != 1 {
+		t.Fatalf("redo stack has %d entries, want 1", len(m.undoHistory.redo))
+	}
+	toggleForTest(&m, "b.go")
+	if len(m.undoHistory.redo) != 0 {
+		t.Errorf("redo stack has %d entries after a new change, want 0", len(m.undoHistory.redo))
+	}
+	m.redo()
+	if want := "Nothing to redo"; m.statusMessage != want {
+		t.Errorf("status = %q, want %q", m.statusMessage, want)
+	}
+}
+
+func TestUndoSkipsNoOpChanges(t *testing.T) {
+	m := newTestModel(t, "a.go")
+
+	before := m.snapshotSelection()
+	clear(m.selected) // Clearing an empty selection changes nothing.
+	m.recordUndo("clear selection", before)
+	if len(m.undoHistory.undo) != 0 {
+		t.Errorf("undo stack has %d entries after a no-op, want 0", len(m.undoHistory.undo))
+	}
+}
+
+func TestUndoStackBound(t *testing.T) {
+	m := newTestModel(t, "a.go")
+
+	for i := 0; i < undoMaxEntries+5; i++ {
+		before := m.snapshotSelection()
+		m.patterns = []string{fmt.Sprintf("p%d/**", i)}
+		m.recordUndo(fmt.Sprintf("change %d", i), before)
+	}
+	if len(m.undoHistory.undo) != undoMaxEntries {
+		t.Fatalf("undo stack has %d entries, want %d", len(m.undoHistory.undo), undoMaxEntries)
+	}
+	// The oldest entries were dropped, so the first remaining one is change 5.
+	if got, want := m.undoHistory.undo[0].description, "change 5"; got != want {
+		t.Errorf("oldest entry = %q, want %q", got, want)
+	}
+}
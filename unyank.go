@@ -0,0 +1,655 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Unyank: Applying Bundles Back to Disk ---
+
+var (
+	// Styles used when printing diffs to the terminal (outside the TUI), set by applyTheme.
+	diffAddStyle    lipgloss.Style
+	diffRemoveStyle lipgloss.Style
+	diffHunkStyle   lipgloss.Style
+)
+
+// bundleHeaderRegexp matches the per-file header line written by performCopyAndSave, e.g.
+// "--- FILENAME: path/to/file.go | Modified: 2025-05-02 17:18:10 | Size: 1024 bytes ---".
+// The metadata parts are optional so that hand-written or LLM-produced headers are accepted.
+var bundleHeaderRegexp = regexp.MustCompile(`^--- FILENAME: (.+?)(?: \| Modified: [^|]*?)?(?: \| Size: (\d+) bytes)? ---\s*$`)
+
+// bundleFile is a single file entry parsed from a yank bundle.
+type bundleFile struct {
+	relativePath string // Path as written in the bundle header (relative to the target directory).
+	content      []byte // File content with the bundle separator removed.
+}
+
+// bundleHeader is a header line located by nextBundleHeader.
+type bundleHeader struct {
+	path      string
+	size      int // -1 if the header carries no size.
+	lineStart int // Offset of the header line itself.
+	bodyStart int // Offset of the first content byte after the header line.
+}
+
+// parseBundle splits a yank bundle into its individual files. Any text before the
+// first header (e.g., an LLM's introductory sentence) is ignored.
+//
+// The bundle is walked file by file rather than by scanning every line for headers:
+// each body is delimited by its recorded size or its closing code fence first, so a
+// file that itself quotes a yank bundle (a Markdown note, a test fixture) is restored
+// as one file instead of being split at the quoted headers.
+//
+// Bundles copied with -html are accepted too: their HTML rendering is converted back to
+// the plain-text form first.
+func parseBundle(data []byte) ([]bundleFile, error) {
+	if isHTMLBundle(data) {
+		data = htmlBundleText(data)
+	}
+	h, ok := nextBundleHeader(data, 0)
+	if !ok {
+		return nil, errors.New("no '--- FILENAME: ... ---' headers found")
+	}
+
+	var files []bundleFile
+	for ok {
+		content, end := bundleBody(data, h)
+		files = append(files, bundleFile{relativePath: h.path, content: bytes.Clone(content)})
+		h, ok = nextBundleHeader(data, end)
+	}
+	return files, nil
+}
+
+// isHTMLBundle reports whether data is an HTML document, such as the rendering written by
+// htmlBundleBuilder, rather than a plain-text bundle.
+func isHTMLBundle(data []byte) bool {
+	start := bytes.ToLower(bytes.TrimSpace(data[:min(len(data), 64)]))
+	return bytes.HasPrefix(start, []byte("<html")) || bytes.HasPrefix(start, []byte("<!doctype html"))
+}
+
+var (
+	// htmlBlockRegexp matches the paragraphs (preamble, file headers) and <pre> blocks (file
+	// contents) written by htmlBundleBuilder.
+	htmlBlockRegexp = regexp.MustCompile(`(?s)<p>(.*?)</p>|<pre\b[^>]*>(.*?)</pre>`)
+	// htmlTagRegexp matches a single tag; the highlighting spans carry no text of their own.
+	htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlBundleText converts an HTML bundle back into the plain-text form written by
+// performCopyAndSave: each paragraph becomes a line, each <pre> block a file body followed
+// by the blank separator line. Since the contents are escaped in full, stripping the tags
+// and unescaping the entities restores them byte for byte, so the recorded sizes still apply.
+func htmlBundleText(data []byte) []byte {
+	var sb strings.Builder
+	for _, match := range htmlBlockRegexp.FindAllSubmatch(data, -1) {
+		if match[1] != nil {
+			sb.WriteString(html.UnescapeString(htmlTagRegexp.ReplaceAllString(string(match[1]), "")))
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(html.UnescapeString(htmlTagRegexp.ReplaceAllString(string(match[2]), "")))
+			sb.WriteString("\n\n")
+		}
+	}
+	return []byte(sb.String())
+}
+
+// nextBundleHeader returns the first header line at or after offset.
+func nextBundleHeader(data []byte, offset int) (bundleHeader, bool) {
+	for offset < len(data) {
+		next := lineEnd(data, offset)
+		line := strings.TrimRight(string(data[offset:next]), "\r\n")
+		if match := bundleHeaderRegexp.FindStringSubmatch(line); match != nil {
+			size := -1
+			if match[2] != "" {
+				if n, err := strconv.Atoi(match[2]); err == nil {
+					size = n
+				}
+			}
+			return bundleHeader{path: strings.TrimSpace(match[1]), size: size, lineStart: offset, bodyStart: next}, true
+		}
+		offset = next
+	}
+	return bundleHeader{}, false
+}
+
+// lineEnd returns the offset just past the line starting at offset (including its newline).
+func lineEnd(data []byte, offset int) int {
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(data)
+}
+
+// bundleBody extracts the content of the file introduced by h and returns it together with
+// the offset at which the search for the next header should resume. In order of preference
+// the body ends at:
+//  1. the recorded size, if only whitespace follows it up to the next header (or the end);
+//  2. the closing fence, if the body is wrapped in a markdown code fence;
+//  3. the next header line (the only option for unfenced, hand-edited bodies).
+func bundleBody(data []byte, h bundleHeader) ([]byte, int) {
+	// --- Recorded Size ---
+	if h.size >= 0 && h.size <= len(data)-h.bodyStart {
+		if resume, ok := onlySpaceUntilHeader(data, h.bodyStart+h.size); ok {
+			return data[h.bodyStart : h.bodyStart+h.size], resume
+		}
+	}
+
+	// --- Code Fence ---
+	// Size missing or wrong (typical for edited bundles): strip the fence and normalise the
+	// trailing separator down to a single newline. A fence that closes early (the file merely
+	// starts with a code block) is not trusted.
+	if content, end, ok := fencedBody(data, h.bodyStart); ok {
+		if resume, ok := onlySpaceUntilHeader(data, end); ok {
+			return normaliseTrailingNewline(content), resume
+		}
+	}
+
+	// --- Next Header ---
+	end := len(data)
+	if next, ok := nextBundleHeader(data, h.bodyStart); ok {
+		end = next.lineStart
+	}
+	return normaliseTrailingNewline(stripCodeFence(data[h.bodyStart:end])), end
+}
+
+// onlySpaceUntilHeader reports whether nothing but whitespace lies between offset and the
+// next header line (or the end of the data), returning the offset to resume the search at.
+func onlySpaceUntilHeader(data []byte, offset int) (int, bool) {
+	end := len(data)
+	if next, ok := nextBundleHeader(data, offset); ok {
+		end = next.lineStart
+	}
+	return end, len(bytes.TrimSpace(data[offset:end])) == 0
+}
+
+// fencedBody reports whether the body starting at offset opens with a markdown code fence
+// (after optional blank lines) and, if so, returns the fenced content and the offset just
+// past the closing fence. The closing fence is a line of only backticks, at least as long
+// as the opening one, as in CommonMark; fences of other lengths inside the body are content.
+func fencedBody(data []byte, offset int) ([]byte, int, bool) {
+	for offset < len(data) && len(bytes.TrimSpace(data[offset:lineEnd(data, offset)])) == 0 {
+		offset = lineEnd(data, offset)
+	}
+	opening := bytes.TrimSpace(data[offset:lineEnd(data, offset)])
+	fenceLen := len(opening) - len(bytes.TrimLeft(opening, "`"))
+	if fenceLen < 3 {
+		return nil, 0, false
+	}
+
+	contentStart := lineEnd(data, offset)
+	for line := contentStart; line < len(data); line = lineEnd(data, line) {
+		trimmed := bytes.TrimSpace(data[line:lineEnd(data, line)])
+		if len(trimmed) >= fenceLen && len(bytes.Trim(trimmed, "`")) == 0 {
+			return data[contentStart:line], lineEnd(data, line), true
+		}
+	}
+	return nil, 0, false
+}
+
+// normaliseTrailingNewline trims trailing line breaks down to a single newline, since
+// bodies delimited by fences or headers carry an unknown amount of separator whitespace.
+func normaliseTrailingNewline(content []byte) []byte {
+	content = bytes.TrimRight(content, "\r\n")
+	if len(content) > 0 {
+		content = append(content[:len(content):len(content)], '\n')
+	}
+	return content
+}
+
+// stripCodeFence removes a surrounding markdown code fence (```lang ... ```) from a
+// file body, which LLMs often add even when asked to reply in the bundle format.
+func stripCodeFence(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("```")) || !bytes.HasSuffix(trimmed, []byte("```")) {
+		return body
+	}
+	firstNewline := bytes.IndexByte(trimmed, '\n')
+	if firstNewline < 0 {
+		return body
+	}
+	return trimmed[firstNewline+1 : len(trimmed)-3]
+}
+
+// resolveBundlePath converts a path from a bundle header into an absolute path inside
+// targetDir. It refuses absolute paths, paths escaping targetDir (via ".." or symlinks),
+// anything inside ".git", and the persistence file itself.
+func resolveBundlePath(targetDir, relativePath string) (string, error) {
+	if relativePath == "" {
+		return "", errors.New("empty path")
+	}
+	if filepath.IsAbs(relativePath) || filepath.VolumeName(relativePath) != "" {
+		return "", fmt.Errorf("absolute path '%s' not allowed", relativePath)
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(relativePath))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path '%s' escapes target directory", relativePath)
+	}
+	for _, part := range strings.Split(cleaned, string(os.PathSeparator)) {
+		if part == ".git" {
+			return "", fmt.Errorf("path '%s' points into .git", relativePath)
+		}
+	}
+	if cleaned == persistenceDotFileName {
+		return "", fmt.Errorf("refusing to overwrite persistence file '%s'", persistenceDotFileName)
+	}
+
+	fullPath := filepath.Join(targetDir, cleaned)
+
+	// --- Symlink Check ---
+	// Resolve the deepest existing ancestor and make sure it still lives inside targetDir,
+	// so a symlinked directory cannot redirect the write elsewhere.
+	realTarget, err := filepath.EvalSymlinks(targetDir)
+	if err != nil {
+		return "", fmt.Errorf("resolving target directory: %w", err)
+	}
+	existing := fullPath
+	for {
+		if _, statErr := os.Lstat(existing); statErr == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	realExisting, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("resolving '%s': %w", relativePath, err)
+	}
+	rel, err := filepath.Rel(realTarget, realExisting)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path '%s' escapes target directory via symlink", relativePath)
+	}
+
+	return fullPath, nil
+}
+
+// --- Line Diff ---
+
+// diffOp is a single line-level edit produced by diffLines.
+type diffOp struct {
+	kind byte // ' ' (unchanged), '-' (removed), '+' (added).
+	text string
+}
+
+// maxDiffCells bounds the size of the LCS table. Larger inputs fall back to a
+// whole-block replacement, which is still correct, just less precise.
+const maxDiffCells = 4_000_000
+
+// diffLines computes a line diff between a and b using a longest-common-subsequence table
+// after trimming the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+
+	// Trim the common prefix and suffix to keep the LCS table small for typical edits.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		for _, line := range midA {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		// lcs[i][j] holds the LCS length of midA[i:] and midB[j:].
+		cols := len(midB) + 1
+		lcs := make([]int, (len(midA)+1)*cols)
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i*cols+j] = lcs[(i+1)*cols+j+1] + 1
+				} else {
+					lcs[i*cols+j] = max(lcs[(i+1)*cols+j], lcs[i*cols+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) && j < len(midB) {
+			switch {
+			case midA[i] == midB[j]:
+				ops = append(ops, diffOp{' ', midA[i]})
+				i++
+				j++
+			case lcs[(i+1)*cols+j] >= lcs[i*cols+j+1]:
+				ops = append(ops, diffOp{'-', midA[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', midB[j]})
+				j++
+			}
+		}
+		for ; i < len(midA); i++ {
+			ops = append(ops, diffOp{'-', midA[i]})
+		}
+		for ; j < len(midB); j++ {
+			ops = append(ops, diffOp{'+', midB[j]})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// splitLines splits content into lines without their trailing newline.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// formatUnifiedDiff renders a unified diff (3 lines of context) between oldContent and
+// newContent. It returns an empty string if the contents are identical.
+func formatUnifiedDiff(relativePath string, oldContent, newContent []byte, oldExists bool) string {
+	if oldExists && bytes.Equal(oldContent, newContent) {
+		return ""
+	}
+	const context = 3
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	var sb strings.Builder
+	oldName := "a/" + filepath.ToSlash(relativePath)
+	if !oldExists {
+		oldName = "/dev/null"
+	}
+	sb.WriteString(diffRemoveStyle.Render("--- "+oldName) + "\n")
+	sb.WriteString(diffAddStyle.Render("+++ b/"+filepath.ToSlash(relativePath)) + "\n")
+
+	// Walk the edit script and emit hunks around each run of changes.
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+		hunkStart := max(start-context, 0)
+		// Extend the hunk while changes are within 2*context lines of each other.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+
+		// Compute the line numbers for the hunk header.
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+		sb.WriteString(diffHunkStyle.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldLine, oldCount, newLine, newCount)) + "\n")
+		for _, op := range ops[hunkStart:end] {
+			line := string(op.kind) + op.text
+			switch op.kind {
+			case '-':
+				line = diffRemoveStyle.Render(line)
+			case '+':
+				line = diffAddStyle.Render(line)
+			}
+			sb.WriteString(line + "\n")
+		}
+		start = end
+	}
+
+	if oldExists && len(oldContent) > 0 && len(newContent) > 0 &&
+		(oldContent[len(oldContent)-1] == '\n') != (newContent[len(newContent)-1] == '\n') {
+		sb.WriteString(helpStyle.Render(`\ Trailing newline differs`) + "\n")
+	}
+	return sb.String()
+}
+
+// --- Command ---
+
+// runUnyank implements the "unyank" subcommand: it parses a bundle from the clipboard
+// (or a file/stdin), shows a diff per file against targetDir, and writes the approved files.
+// It returns the process exit code.
+func runUnyank(args []string) int {
+	fset := flag.NewFlagSet(appName+" unyank", flag.ContinueOnError)
+	dir := fset.String("dir", ".", "Directory to apply the bundle to")
+	input := fset.String("in", "", "Read the bundle from this file ('-' for stdin) instead of the clipboard")
+	assumeYes := fset.Bool("y", false, "Apply all changed files without prompting")
+	dryRun := fset.Bool("n", false, "Dry run: show diffs but do not write anything")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage:\n  %s unyank [-dir <directory>] [-in <file>|-] [-y] [-n]\n\nOptions:\n", appName)
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	targetDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path '%s': %v\n", *dir, err)
+		return 1
+	}
+	if _, statErr := os.Stat(targetDir); statErr != nil {
+		fmt.Fprintf(os.Stderr, "Target directory '%s' error: %v\n", targetDir, statErr)
+		return 1
+	}
+
+	// --- Read Bundle ---
+	var data []byte
+	switch *input {
+	case "":
+		text, clipErr := readFromClipboard()
+		if clipErr != nil {
+			fmt.Fprintf(os.Stderr, "Error reading clipboard: %v\n", clipErr)
+			return 1
+		}
+		data = []byte(text)
+	case "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(*input)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bundle: %v\n", err)
+		return 1
+	}
+
+	files, err := parseBundle(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing bundle: %v\n", err)
+		return 1
+	}
+
+	// --- Prompt Source ---
+	// When the bundle itself arrives on stdin, answers have to come from the terminal.
+	var prompt *bufio.Reader
+	if !*assumeYes && !*dryRun {
+		promptInput := io.Reader(os.Stdin)
+		if *input == "-" {
+			tty, ttyErr := openTTY()
+			if ttyErr != nil {
+				fmt.Fprintf(os.Stderr, "Error opening terminal for prompts (use -y to skip prompts): %v\n", ttyErr)
+				return 1
+			}
+			defer tty.Close()
+			promptInput = tty
+		}
+		prompt = bufio.NewReader(promptInput)
+	}
+
+	// --- Review and Apply Each File ---
+	written, skipped, refused, unchanged := 0, 0, 0, 0
+	applyAll := *assumeYes
+	for _, f := range files {
+		fullPath, pathErr := resolveBundlePath(targetDir, f.relativePath)
+		if pathErr != nil {
+			fmt.Println(errorStyle.Render(fmt.Sprintf("Refusing '%s': %v", f.relativePath, pathErr)))
+			refused++
+			continue
+		}
+
+		oldContent, readErr := os.ReadFile(fullPath)
+		oldExists := readErr == nil
+		if readErr != nil && !errors.Is(readErr, fs.ErrNotExist) {
+			fmt.Println(errorStyle.Render(fmt.Sprintf("Refusing '%s': %v", f.relativePath, readErr)))
+			refused++
+			continue
+		}
+
+		diff := formatUnifiedDiff(f.relativePath, oldContent, f.content, oldExists)
+		if diff == "" {
+			fmt.Println(helpStyle.Render(fmt.Sprintf("Unchanged: %s", f.relativePath)))
+			unchanged++
+			continue
+		}
+		status := "Modified"
+		if !oldExists {
+			status = "New file"
+		}
+		fmt.Printf("\n%s\n%s", titleStyle.Render(fmt.Sprintf("%s: %s", status, f.relativePath)), diff)
+
+		if *dryRun {
+			skipped++
+			continue
+		}
+
+		if !applyAll {
+			answer := askApply(prompt, f.relativePath)
+			switch answer {
+			case 'a':
+				applyAll = true
+			case 'q':
+				fmt.Println("Aborted.")
+				skipped += len(files) - written - skipped - refused - unchanged
+				printUnyankSummary(written, skipped, refused, unchanged)
+				return 0
+			case 'n':
+				skipped++
+				continue
+			}
+		}
+
+		if writeErr := writeBundleFile(fullPath, f.content); writeErr != nil {
+			fmt.Println(errorStyle.Render(fmt.Sprintf("Error writing '%s': %v", f.relativePath, writeErr)))
+			refused++
+			continue
+		}
+		written++
+	}
+
+	printUnyankSummary(written, skipped, refused, unchanged)
+	if refused > 0 {
+		return 1
+	}
+	return 0
+}
+
+// askApply prompts for a single file and returns 'y', 'n', 'a' (all), or 'q' (quit).
+// EOF on the prompt input is treated as quit.
+func askApply(prompt *bufio.Reader, relativePath string) byte {
+	for {
+		fmt.Printf("Apply changes to %s? [y]es/[n]o/[a]ll/[q]uit: ", relativePath)
+		line, err := prompt.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer != "" {
+			switch answer[0] {
+			case 'y', 'n', 'a', 'q':
+				return answer[0]
+			}
+		}
+		if err != nil {
+			fmt.Println()
+			return 'q'
+		}
+	}
+}
+
+// writeBundleFile writes content to fullPath atomically, creating parent directories as
+// needed and preserving the permission bits of an existing file. A crash or a full disk
+// thus leaves either the old or the new content, never a truncated file. A symlink is
+// written through (resolveBundlePath has checked that it stays inside the target
+// directory), so it is not replaced by a regular file.
+func writeBundleFile(fullPath string, content []byte) error {
+	perm := fs.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		perm = info.Mode().Perm()
+		if realPath, err := filepath.EvalSymlinks(fullPath); err == nil {
+			fullPath = realPath
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(fullPath, content, perm)
+}
+
+// printUnyankSummary prints the final tally of the unyank run.
+func printUnyankSummary(written, skipped, refused, unchanged int) {
+	fmt.Printf("\nWrote %d file(s), skipped %d, unchanged %d, refused %d.\n", written, skipped, unchanged, refused)
+}
+
+// openTTY opens the controlling terminal for interactive prompts.
+func openTTY() (*os.File, error) {
+	if runtime.GOOS == "windows" {
+		return os.Open("CONIN$")
+	}
+	return os.Open("/dev/tty")
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseBundle(t *testing.T) {
+	// quoted is a Markdown file that documents the bundle format by quoting a header.
+	quoted := "# Bundles\n\nA bundle looks like this:\n\n--- FILENAME: other.go | Size: 3 bytes ---\nabc\n"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		want    []bundleFile
+	}{
+		{
+			name:    "no headers",
+			data:    "just some text\n",
+			wantErr: true,
+		},
+		{
+			name: "as written by yank",
+			data: "--- FILENAME: a.go | Modified: 2025-05-02 17:18:10 | Size: 4 bytes ---\na b\n\n\n" +
+				"--- FILENAME: dir/b.txt | Modified: 2025-05-02 17:18:10 | Size: 2 bytes ---\nxy\n\n",
+			want: []bundleFile{
+				{relativePath: "a.go", content: []byte("a b\n")},
+				{relativePath: "dir/b.txt", content: []byte("xy")},
+			},
+		},
+		{
+			name: "preamble and headers without metadata",
+			data: "Here are the changes:\n\n--- FILENAME: a.go ---\npackage a\n\n\n--- FILENAME: b.go ---\npackage b",
+			want: []bundleFile{
+				{relativePath: "a.go", content: []byte("package a\n")},
+				{relativePath: "b.go", content: []byte("package b\n")},
+			},
+		},
+		{
+			name: "fenced bodies",
+			data: "--- FILENAME: a.go ---\n```go\npackage a\n```\n\n--- FILENAME: b.go ---\n\n```\npackage b\n```\n",
+			want: []bundleFile{
+				{relativePath: "a.go", content: []byte("package a\n")},
+				{relativePath: "b.go", content: []byte("package b\n")},
+			},
+		},
+		{
+			name: "wrong size falls back to the next header",
+			data: "--- FILENAME: a.go | Size: 2 bytes ---\npackage a\n\n--- FILENAME: b.go | Size: 9 bytes ---\npackage b\n\n",
+			want: []bundleFile{
+				{relativePath: "a.go", content: []byte("package a\n")},
+				{relativePath: "b.go", content: []byte("package b")},
+			},
+		},
+		{
+			name: "quoted header inside a sized body",
+			data: "--- FILENAME: docs/bundle.md | Size: " + strconv.Itoa(len(quoted)) + " bytes ---\n" + quoted + "\n\n" +
+				"--- FILENAME: main.go | Size: 13 bytes ---\npackage main\n\n\n",
+			want: []bundleFile{
+				{relativePath: "docs/bundle.md", content: []byte(quoted)},
+				{relativePath: "main.go", content: []byte("package main\n")},
+			},
+		},
+		{
+			name: "quoted header inside a fenced body",
+			data: "--- FILENAME: docs/bundle.md ---\n````markdown\n" + quoted + "```\nnot the end\n```\n````\n\n" +
+				"--- FILENAME: main.go ---\npackage main\n",
+			want: []bundleFile{
+				{relativePath: "docs/bundle.md", content: []byte(quoted + "```\nnot the end\n```\n")},
+				{relativePath: "main.go", content: []byte("package main\n")},
+			},
+		},
+		{
+			name: "body that merely starts with a code block",
+			data: "--- FILENAME: README.md ---\n```sh\nmake\n```\n\nRun it.\n--- FILENAME: main.go ---\npackage main\n",
+			want: []bundleFile{
+				{relativePath: "README.md", content: []byte("```sh\nmake\n```\n\nRun it.\n")},
+				{relativePath: "main.go", content: []byte("package main\n")},
+			},
+		},
+		{
+			name: "empty file",
+			data: "--- FILENAME: empty.txt | Size: 0 bytes ---\n\n\n--- FILENAME: a.go ---\npackage a\n",
+			want: []bundleFile{
+				{relativePath: "empty.txt", content: []byte{}},
+				{relativePath: "a.go", content: []byte("package a\n")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parseBundle([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d file(s)", len(files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != len(tt.want) {
+				t.Fatalf("got %d file(s) %q, want %d", len(files), files, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if files[i].relativePath != want.relativePath {
+					t.Errorf("file %d path = %q, want %q", i, files[i].relativePath, want.relativePath)
+				}
+				if string(files[i].content) != string(want.content) {
+					t.Errorf("file %d (%s) content = %q, want %q", i, want.relativePath, files[i].content, want.content)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveBundlePath(t *testing.T) {
+	target := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(target, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(target, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(target, "sub"), filepath.Join(target, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string // Substring of the expected error; "" if the path is accepted.
+		want    string // Expected result relative to target.
+	}{
+		{name: "plain file", path: "main.go", want: "main.go"},
+		{name: "new nested file", path: "sub/new/deep.go", want: "sub/new/deep.go"},
+		{name: "cleaned path", path: "sub/../main.go", want: "main.go"},
+		{name: "symlink staying inside", path: "inside/x.go", want: "inside/x.go"},
+		{name: "empty", path: "", wantErr: "empty path"},
+		{name: "absolute", path: "/etc/passwd", wantErr: "absolute path"},
+		{name: "dot", path: ".", wantErr: "escapes target"},
+		{name: "parent", path: "..", wantErr: "escapes target"},
+		{name: "parent prefix", path: "../x.go", wantErr: "escapes target"},
+		{name: "parent after cleaning", path: "sub/../../x.go", wantErr: "escapes target"},
+		{name: "git directory", path: ".git/config", wantErr: ".git"},
+		{name: "nested git directory", path: "vendor/lib/.git/HEAD", wantErr: ".git"},
+		{name: "persistence file", path: persistenceDotFileName, wantErr: "persistence file"},
+		{name: "symlink escape", path: "escape/x.go", wantErr: "via symlink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveBundlePath(target, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveBundlePath(%q) = %q, %v; want an error containing %q", tt.path, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filepath.Join(target, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("resolveBundlePath(%q) = %q, want %q", tt.path, got, want)
+			}
+		})
+	}
+}
+
+func TestWriteBundleFile(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("old\n"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBundleFile(script, []byte("new\n")); err != nil {
+		t.Fatalf("writeBundleFile: %v", err)
+	}
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o750 {
+		t.Errorf("mode = %v, want the original -rwxr-x---", info.Mode().Perm())
+	}
+	if got, _ := os.ReadFile(script); string(got) != "new\n" {
+		t.Errorf("content = %q, want %q", got, "new\n")
+	}
+
+	nested := filepath.Join(dir, "a", "b", "c.go")
+	if err := writeBundleFile(nested, []byte("package c\n")); err != nil {
+		t.Fatalf("writeBundleFile(new nested file): %v", err)
+	}
+	if got, _ := os.ReadFile(nested); string(got) != "package c\n" {
+		t.Errorf("nested content = %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temporary file %q left behind", e.Name())
+		}
+	}
+}
+
+func TestParseHTMLBundle(t *testing.T) {
+	want := []bundleFile{
+		{relativePath: "a.go", content: []byte("package a\n\nfunc x() string {\n\treturn \"<p>&amp;</p>\"\n}\n")},
+		{relativePath: "notes.md", content: []byte("--- FILENAME: quoted.go ---\nno trailing newline")},
+		{relativePath: "empty.txt", content: []byte{}},
+	}
+	b := newHTMLBundleBuilder()
+	b.addPreamble("Review <these> files.\nThanks.")
+	for _, f := range want {
+		b.addFile(f.relativePath, fmt.Sprintf("--- FILENAME: %s | Modified: 2025-05-02 17:18:10 | Size: %d bytes ---\n", f.relativePath, len(f.content)), f.content)
+	}
+
+	files, err := parseBundle([]byte(b.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d file(s) %q, want %d", len(files), files, len(want))
+	}
+	for i, w := range want {
+		if files[i].relativePath != w.relativePath || string(files[i].content) != string(w.content) {
+			t.Errorf("file %d = %q %q, want %q %q", i, files[i].relativePath, files[i].content, w.relativePath, w.content)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Visual Range Selection ---
+
+// visualStyle highlights the rows of the visual range (set by applyTheme).
+var visualStyle lipgloss.Style
+
+// visualMode holds the state of a vim-style visual range selection.
+type visualMode struct {
+	active bool // Flag set while a range is being selected.
+	// Path where the range started; the cursor is the other end. A path rather than a list
+	// index, so the range follows its file when the list is sorted or reshaped.
+	anchor string
+}
+
+// startVisual anchors a range at the cursor.
+func (m *model) startVisual() {
+	m.visual = visualMode{active: true}
+	if current, ok := m.list.SelectedItem().(item); ok {
+		m.visual.anchor = current.name
+	}
+	m.updateDelegate()
+}
+
+// stopVisual leaves visual mode.
+func (m *model) stopVisual() {
+	m.visual = visualMode{}
+	m.updateDelegate()
+}
+
+// visualAnchor returns the list index of the anchor. If its file is no longer listed (e.g.,
+// hidden files were hidden), the range shrinks to the cursor.
+func (m *model) visualAnchor() int {
+	for i, listItem := range m.list.Items() {
+		if li, ok := listItem.(item); ok && li.name == m.visual.anchor {
+			return i
+		}
+	}
+	return m.list.Index()
+}
+
+// visualRange returns the paths between the anchor and the cursor (inclusive).
+func (m *model) visualRange() []string {
+	items := m.list.Items()
+	if len(items) == 0 {
+		return nil
+	}
+	anchor := m.visualAnchor()
+	lo, hi := min(anchor, m.list.Index()), max(anchor, m.list.Index())
+	paths := make([]string, 0, hi-lo+1)
+	for _, listItem := range items[lo : hi+1] {
+		if li, ok := listItem.(item); ok {
+			paths = append(paths, li.name)
+		}
+	}
+	return paths
+}
+
+// updateVisual handles the keys acting on the range. It reports whether it handled msg; other
+// keys (notably navigation) keep their normal meaning and move the cursor end of the range.
+func (m *model) updateVisual(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, m.keys.VisualExit):
+		m.stopVisual()
+		return nil, true
+
+	case key.Matches(msg, m.keys.VisualToggle):
+		// Toggle the range as a whole: fully selected ranges are cleared, others completed.
+		paths := m.visualRange()
+		allSelected := true
+		for _, relativePath := range paths {
+			allSelected = allSelected && m.isEffectivelySelected(relativePath)
+		}
+		if allSelected {
+			return m.applyVisual(paths, false), true
+		}
+		return m.applyVisual(paths, true), true
+
+	case key.Matches(msg, m.keys.VisualSelect):
+		return m.applyVisual(m.visualRange(), true), true
+
+	case key.Matches(msg, m.keys.VisualDeselect):
+		return m.applyVisual(m.visualRange(), false), true
+	}
+	return nil, false
+}
+
+// applyVisual selects or deselects the range and leaves visual mode.
+func (m *model) applyVisual(paths []string, selected bool) tea.Cmd {
+	before := m.snapshotSelection()
+	m.stopVisual()
+	if selected {
+		changed := m.selectPaths(paths)
+		return m.applyBulkChange(fmt.Sprintf("select range of %d", len(paths)), before, fmt.Sprintf("Selected %d file(s) in range", changed))
+	}
+	changed := m.deselectPaths(paths)
+	return m.applyBulkChange(fmt.Sprintf("deselect range of %d", len(paths)), before, fmt.Sprintf("Deselected %d file(s) in range", changed))
+}